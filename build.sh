@@ -1,3 +1,4 @@
 go build -o sched-only ./sched-cmd
 go build -o sched      ./sched-exec
 go build -o exec       ./executor
+go build -o sim        ./sim-cluster
@@ -0,0 +1,267 @@
+package constraint
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vladimirvivien/mesos-http/mesos/mesos"
+)
+
+// Operator is a Marathon-style placement operator
+type Operator int
+
+const (
+	Unique Operator = iota
+	Cluster
+	GroupBy
+	Like
+	Unlike
+	MaxPer
+)
+
+var operatorNames = map[Operator]string{
+	Unique:  "UNIQUE",
+	Cluster: "CLUSTER",
+	GroupBy: "GROUP_BY",
+	Like:    "LIKE",
+	Unlike:  "UNLIKE",
+	MaxPer:  "MAX_PER",
+}
+
+func (o Operator) String() string {
+	if name, ok := operatorNames[o]; ok {
+		return name
+	}
+	return fmt.Sprintf("Operator(%d)", int(o))
+}
+
+// Hostname is the field name that matches the offer hostname
+// instead of an agent attribute.
+const Hostname = "hostname"
+
+// Constraint restricts the offers a task can be placed on based
+// on the value of an agent attribute (or hostname).
+type Constraint struct {
+	Field    string
+	Operator Operator
+	Value    string
+
+	re    *regexp.Regexp
+	count int
+}
+
+// Placement records where a task has already been placed
+type Placement struct {
+	Hostname   string
+	Attributes []*mesos.Attribute
+}
+
+// New returns a validated constraint
+func New(field string, op Operator, value string) (*Constraint, error) {
+	c := &Constraint{Field: field, Operator: op, Value: value}
+	if field == "" {
+		return nil, fmt.Errorf("constraint: missing field name")
+	}
+
+	switch op {
+	case Unique:
+	case Cluster:
+	case Like, Unlike:
+		if value == "" {
+			return nil, fmt.Errorf("constraint: %s on %s requires a regex", op, field)
+		}
+		re, err := regexp.Compile("^(?:" + value + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("constraint: invalid regex %q: %s", value, err)
+		}
+		c.re = re
+	case GroupBy, MaxPer:
+		if value == "" {
+			if op == MaxPer {
+				return nil, fmt.Errorf("constraint: MAX_PER on %s requires a count", field)
+			}
+			break
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("constraint: %s on %s requires a positive count, got %q", op, field, value)
+		}
+		c.count = n
+	default:
+		return nil, fmt.Errorf("constraint: unknown operator %d", int(op))
+	}
+	return c, nil
+}
+
+// Parse parses a constraint of the form field:OPERATOR[:value],
+// e.g. "rack:UNIQUE", "rack:GROUP_BY:3" or "hostname:LIKE:web-.*".
+func Parse(s string) (*Constraint, error) {
+	parts := strings.SplitN(s, ":", 3)
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("constraint: expecting field:OPERATOR[:value], got %q", s)
+	}
+
+	var op Operator
+	found := false
+	for o, name := range operatorNames {
+		if strings.EqualFold(parts[1], name) {
+			op, found = o, true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("constraint: unknown operator %q", parts[1])
+	}
+
+	var value string
+	if len(parts) == 3 {
+		value = parts[2]
+	}
+	return New(parts[0], op, value)
+}
+
+// ParseList parses a comma-separated list of constraints.
+// Regexes used with LIKE and UNLIKE therefore cannot contain commas.
+func ParseList(s string) ([]*Constraint, error) {
+	var list []*Constraint
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		c, err := Parse(item)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, c)
+	}
+	return list, nil
+}
+
+func (c *Constraint) String() string {
+	if c.Value == "" {
+		return fmt.Sprintf("%s:%s", c.Field, c.Operator)
+	}
+	return fmt.Sprintf("%s:%s:%s", c.Field, c.Operator, c.Value)
+}
+
+// Satisfied returns true when placing a task on the offer's agent
+// satisfies the constraint, given the tasks already placed.
+func (c *Constraint) Satisfied(offer *mesos.Offer, placed []Placement) bool {
+	value, ok := fieldValue(c.Field, offer.GetHostname(), offer.GetAttributes())
+
+	switch c.Operator {
+	case Unlike:
+		return !ok || !c.re.MatchString(value)
+	case Like:
+		return ok && c.re.MatchString(value)
+	}
+
+	if !ok {
+		return false
+	}
+	counts := groupCounts(c.Field, placed)
+
+	switch c.Operator {
+	case Unique:
+		return counts[value] == 0
+
+	case Cluster:
+		if c.Value != "" {
+			return value == c.Value
+		}
+		for v := range counts {
+			if v != value {
+				return false
+			}
+		}
+		return true
+
+	case MaxPer:
+		return counts[value] < c.count
+
+	case GroupBy:
+		// the value must be one of the least populated groups; while
+		// fewer groups than expected are known, only new groups qualify.
+		min := -1
+		for _, n := range counts {
+			if min < 0 || n < min {
+				min = n
+			}
+		}
+		if min < 0 || len(counts) < c.count {
+			min = 0
+		}
+		return counts[value] <= min
+	}
+	return false
+}
+
+// Satisfied returns true when the offer satisfies all constraints
+func Satisfied(constraints []*Constraint, offer *mesos.Offer, placed []Placement) bool {
+	for _, c := range constraints {
+		if !c.Satisfied(offer, placed) {
+			return false
+		}
+	}
+	return true
+}
+
+// PlacementOf returns the placement of a task launched on the offer
+func PlacementOf(offer *mesos.Offer) Placement {
+	return Placement{
+		Hostname:   offer.GetHostname(),
+		Attributes: offer.GetAttributes(),
+	}
+}
+
+func groupCounts(field string, placed []Placement) map[string]int {
+	counts := make(map[string]int)
+	for _, p := range placed {
+		if v, ok := fieldValue(field, p.Hostname, p.Attributes); ok {
+			counts[v]++
+		}
+	}
+	return counts
+}
+
+// fieldValue returns the string form of the named field
+func fieldValue(field, hostname string, attrs []*mesos.Attribute) (string, bool) {
+	if field == Hostname {
+		return hostname, true
+	}
+	for _, attr := range attrs {
+		if attr.GetName() == field {
+			return AttributeValue(attr), true
+		}
+	}
+	return "", false
+}
+
+// AttributeValue renders an attribute value as text: scalars as
+// numbers, ranges as [b-e,...] and sets as {a,b,...}.
+func AttributeValue(attr *mesos.Attribute) string {
+	switch attr.GetType() {
+	case mesos.Value_SCALAR:
+		return strconv.FormatFloat(attr.GetScalar().GetValue(), 'f', -1, 64)
+
+	case mesos.Value_RANGES:
+		var ranges []string
+		for _, r := range attr.GetRanges().GetRange() {
+			ranges = append(ranges, fmt.Sprintf("%d-%d", r.GetBegin(), r.GetEnd()))
+		}
+		return "[" + strings.Join(ranges, ",") + "]"
+
+	case mesos.Value_SET:
+		items := append([]string(nil), attr.GetSet().GetItem()...)
+		sort.Strings(items)
+		return "{" + strings.Join(items, ",") + "}"
+
+	case mesos.Value_TEXT:
+		return attr.GetText().GetValue()
+	}
+	return ""
+}
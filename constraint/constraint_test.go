@@ -0,0 +1,179 @@
+package constraint
+
+import (
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/vladimirvivien/mesos-http/mesos/mesos"
+)
+
+func text(name, value string) *mesos.Attribute {
+	return &mesos.Attribute{
+		Name: proto.String(name),
+		Type: mesos.Value_TEXT.Enum(),
+		Text: &mesos.Value_Text{Value: proto.String(value)},
+	}
+}
+
+func offer(host, rack string) *mesos.Offer {
+	o := &mesos.Offer{Hostname: proto.String(host)}
+	if rack != "" {
+		o.Attributes = []*mesos.Attribute{text("rack", rack)}
+	}
+	return o
+}
+
+func placed(racks ...string) []Placement {
+	var p []Placement
+	for i, rack := range racks {
+		p = append(p, PlacementOf(offer(string(rune('a'+i)), rack)))
+	}
+	return p
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{"rack:UNIQUE", "rack:UNIQUE", true},
+		{"rack:unique", "rack:UNIQUE", true},
+		{"rack:CLUSTER:r1", "rack:CLUSTER:r1", true},
+		{"rack:GROUP_BY", "rack:GROUP_BY", true},
+		{"rack:GROUP_BY:3", "rack:GROUP_BY:3", true},
+		{"hostname:LIKE:web-.*", "hostname:LIKE:web-.*", true},
+		{"rack:MAX_PER:2", "rack:MAX_PER:2", true},
+		{"rack", "", false},
+		{":UNIQUE", "", false},
+		{"rack:NEAR", "", false},
+		{"rack:LIKE", "", false},
+		{"rack:LIKE:(", "", false},
+		{"rack:MAX_PER", "", false},
+		{"rack:MAX_PER:0", "", false},
+		{"rack:GROUP_BY:x", "", false},
+	}
+	for _, test := range tests {
+		c, err := Parse(test.in)
+		if (err == nil) != test.ok {
+			t.Errorf("%q: got error %v, want ok %v", test.in, err, test.ok)
+			continue
+		}
+		if err == nil && c.String() != test.want {
+			t.Errorf("%q: parsed as %q, want %q", test.in, c.String(), test.want)
+		}
+	}
+}
+
+func TestParseList(t *testing.T) {
+	list, err := ParseList(" rack:UNIQUE, ,hostname:LIKE:h.*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].Field != "rack" || list[1].Operator != Like {
+		t.Fatalf("unexpected constraints %v", list)
+	}
+	if _, err := ParseList("rack:UNIQUE,rack"); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestSatisfied(t *testing.T) {
+	tests := []struct {
+		constraint string
+		offer      *mesos.Offer
+		placed     []Placement
+		want       bool
+	}{
+		{"rack:UNIQUE", offer("h", "r1"), placed("r2"), true},
+		{"rack:UNIQUE", offer("h", "r1"), placed("r1"), false},
+		{"rack:UNIQUE", offer("h", ""), nil, false},
+		{"hostname:UNIQUE", offer("a", ""), placed("r1"), false},
+		{"hostname:UNIQUE", offer("h", ""), placed("r1"), true},
+
+		{"rack:CLUSTER:r1", offer("h", "r1"), nil, true},
+		{"rack:CLUSTER:r1", offer("h", "r2"), nil, false},
+		{"rack:CLUSTER", offer("h", "r2"), nil, true},
+		{"rack:CLUSTER", offer("h", "r2"), placed("r2", "r2"), true},
+		{"rack:CLUSTER", offer("h", "r2"), placed("r1"), false},
+
+		{"rack:LIKE:r[12]", offer("h", "r1"), nil, true},
+		{"rack:LIKE:r[12]", offer("h", "r3"), nil, false},
+		// the regex matches the whole value
+		{"rack:LIKE:r", offer("h", "r1"), nil, false},
+		{"rack:LIKE:r.*", offer("h", ""), nil, false},
+		{"rack:UNLIKE:r1", offer("h", "r1"), nil, false},
+		{"rack:UNLIKE:r1", offer("h", "r2"), nil, true},
+		{"rack:UNLIKE:r1", offer("h", ""), nil, true},
+
+		{"rack:MAX_PER:2", offer("h", "r1"), placed("r1"), true},
+		{"rack:MAX_PER:2", offer("h", "r1"), placed("r1", "r1"), false},
+		{"rack:MAX_PER:2", offer("h", "r2"), placed("r1", "r1"), true},
+
+		{"rack:GROUP_BY", offer("h", "r1"), nil, true},
+		{"rack:GROUP_BY", offer("h", "r1"), placed("r1"), true},
+		{"rack:GROUP_BY", offer("h", "r1"), placed("r1", "r2"), true},
+		{"rack:GROUP_BY", offer("h", "r1"), placed("r1", "r2", "r1"), false},
+		// until three groups are known only new groups qualify
+		{"rack:GROUP_BY:3", offer("h", "r1"), placed("r1", "r2"), false},
+		{"rack:GROUP_BY:3", offer("h", "r3"), placed("r1", "r2"), true},
+		{"rack:GROUP_BY:3", offer("h", "r1"), placed("r1", "r2", "r3"), true},
+	}
+	for i, test := range tests {
+		c, err := Parse(test.constraint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := c.Satisfied(test.offer, test.placed); got != test.want {
+			t.Errorf("%d: %s: got %v, want %v", i, test.constraint, got, test.want)
+		}
+	}
+}
+
+func TestSatisfiedAll(t *testing.T) {
+	list, err := ParseList("rack:UNIQUE,hostname:LIKE:web-.*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Satisfied(list, offer("web-1", "r1"), placed("r2")) {
+		t.Error("expected all constraints to be satisfied")
+	}
+	if Satisfied(list, offer("db-1", "r1"), placed("r2")) {
+		t.Error("hostname constraint ignored")
+	}
+	if Satisfied(list, offer("web-1", "r2"), placed("r2")) {
+		t.Error("rack constraint ignored")
+	}
+}
+
+func TestAttributeValue(t *testing.T) {
+	tests := []struct {
+		attr *mesos.Attribute
+		want string
+	}{
+		{text("rack", "r1"), "r1"},
+		{&mesos.Attribute{
+			Name:   proto.String("zone"),
+			Type:   mesos.Value_SCALAR.Enum(),
+			Scalar: &mesos.Value_Scalar{Value: proto.Float64(2)},
+		}, "2"},
+		{&mesos.Attribute{
+			Name: proto.String("ports"),
+			Type: mesos.Value_RANGES.Enum(),
+			Ranges: &mesos.Value_Ranges{Range: []*mesos.Value_Range{
+				{Begin: proto.Uint64(1), End: proto.Uint64(2)},
+				{Begin: proto.Uint64(5), End: proto.Uint64(9)},
+			}},
+		}, "[1-2,5-9]"},
+		{&mesos.Attribute{
+			Name: proto.String("disks"),
+			Type: mesos.Value_SET.Enum(),
+			Set:  &mesos.Value_Set{Item: []string{"ssd", "hdd"}},
+		}, "{hdd,ssd}"},
+	}
+	for _, test := range tests {
+		if got := AttributeValue(test.attr); got != test.want {
+			t.Errorf("%s: got %q, want %q", test.attr.GetName(), got, test.want)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package container
+
+import (
+	"fmt"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/vladimirvivien/mesos-http/mesos/mesos"
+)
+
+var networks = map[string]mesos.ContainerInfo_DockerInfo_Network{
+	"host":   mesos.ContainerInfo_DockerInfo_HOST,
+	"bridge": mesos.ContainerInfo_DockerInfo_BRIDGE,
+	"none":   mesos.ContainerInfo_DockerInfo_NONE,
+	"user":   mesos.ContainerInfo_DockerInfo_USER,
+}
+
+// Build returns the ContainerInfo of a spec, mapping the spec ports
+// to hostPorts, which must hold one allocated port per spec port.
+// The result is validated.
+func Build(spec *Spec, hostPorts []uint32) (*mesos.ContainerInfo, error) {
+	if len(hostPorts) != len(spec.Ports) {
+		return nil, fmt.Errorf("container: %d host ports for %d port mappings", len(hostPorts), len(spec.Ports))
+	}
+
+	ci := new(mesos.ContainerInfo)
+	for _, v := range spec.Volumes {
+		vol := &mesos.Volume{
+			ContainerPath: proto.String(v.ContainerPath),
+			Mode:          mesos.Volume_RW.Enum(),
+		}
+		if v.ReadOnly {
+			vol.Mode = mesos.Volume_RO.Enum()
+		}
+		if v.HostPath != "" {
+			vol.HostPath = proto.String(v.HostPath)
+		}
+		ci.Volumes = append(ci.Volumes, vol)
+	}
+	for _, name := range spec.Networks {
+		ci.NetworkInfos = append(ci.NetworkInfos, &mesos.NetworkInfo{Name: proto.String(name)})
+	}
+
+	switch spec.Type {
+	case Docker:
+		ci.Type = mesos.ContainerInfo_DOCKER.Enum()
+		docker := &mesos.ContainerInfo_DockerInfo{
+			Image:          proto.String(spec.Image),
+			Privileged:     proto.Bool(spec.Privileged),
+			ForcePullImage: proto.Bool(spec.ForcePull),
+		}
+		if spec.Network != "" {
+			network, ok := networks[spec.Network]
+			if !ok {
+				return nil, fmt.Errorf("container: unknown network mode %s", spec.Network)
+			}
+			docker.Network = network.Enum()
+		}
+		for i, p := range spec.Ports {
+			docker.PortMappings = append(docker.PortMappings, &mesos.ContainerInfo_DockerInfo_PortMapping{
+				HostPort:      proto.Uint32(hostPorts[i]),
+				ContainerPort: proto.Uint32(p.ContainerPort),
+				Protocol:      proto.String(p.Protocol),
+			})
+		}
+		for _, p := range spec.Parameters {
+			docker.Parameters = append(docker.Parameters, &mesos.Parameter{
+				Key:   proto.String(p.Key),
+				Value: proto.String(p.Value),
+			})
+		}
+		ci.Docker = docker
+
+	case Mesos:
+		ci.Type = mesos.ContainerInfo_MESOS.Enum()
+		ci.Mesos = new(mesos.ContainerInfo_MesosInfo)
+		if spec.Image != "" {
+			ci.Mesos.Image = &mesos.Image{
+				Type:   mesos.Image_DOCKER.Enum(),
+				Docker: &mesos.Image_Docker{Name: proto.String(spec.Image)},
+			}
+		}
+		if spec.Network != "" && spec.Network != "host" && spec.Network != "user" {
+			return nil, fmt.Errorf("container: network mode %s requires the docker containerizer", spec.Network)
+		}
+
+	default:
+		return nil, fmt.Errorf("container: unknown containerizer %q", spec.Type)
+	}
+
+	if err := Validate(ci); err != nil {
+		return nil, err
+	}
+	return ci, nil
+}
+
+// Check validates a spec before any port is allocated
+func (spec *Spec) Check() error {
+	ports := make([]uint32, len(spec.Ports))
+	for i := range ports {
+		ports[i] = uint32(i + 1)
+	}
+	_, err := Build(spec, ports)
+	return err
+}
@@ -0,0 +1,78 @@
+package container
+
+import (
+	"sort"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/vladimirvivien/mesos-http/mesos/mesos"
+)
+
+// PortPool hands out the ports of an offer
+type PortPool struct {
+	ranges [][2]uint64
+}
+
+// NewPortPool returns a pool of the unreserved, non-revocable ports
+// among resources, those PortsResource allocates from.
+func NewPortPool(resources []*mesos.Resource) *PortPool {
+	p := new(PortPool)
+	for _, res := range resources {
+		if res.GetName() != "ports" || res.GetRole() != "*" || res.Reservation != nil || res.Revocable != nil {
+			continue
+		}
+		for _, r := range res.GetRanges().GetRange() {
+			if r.GetBegin() <= r.GetEnd() {
+				p.ranges = append(p.ranges, [2]uint64{r.GetBegin(), r.GetEnd()})
+			}
+		}
+	}
+	return p
+}
+
+// Take allocates n ports, lowest first. It allocates nothing and
+// returns false if fewer than n ports are left.
+func (p *PortPool) Take(n int) ([]uint32, bool) {
+	available := uint64(0)
+	for _, r := range p.ranges {
+		available += r[1] - r[0] + 1
+	}
+	if uint64(n) > available {
+		return nil, false
+	}
+
+	ports := make([]uint32, 0, n)
+	for len(ports) < n {
+		r := &p.ranges[0]
+		ports = append(ports, uint32(r[0]))
+		if r[0] == r[1] {
+			p.ranges = p.ranges[1:]
+		} else {
+			r[0]++
+		}
+	}
+	return ports, true
+}
+
+// PortsResource returns the unreserved ports resource holding ports
+func PortsResource(ports []uint32) *mesos.Resource {
+	sorted := append([]uint32(nil), ports...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	ranges := new(mesos.Value_Ranges)
+	for _, port := range sorted {
+		n := len(ranges.Range)
+		if n > 0 && ranges.Range[n-1].GetEnd()+1 == uint64(port) {
+			ranges.Range[n-1].End = proto.Uint64(uint64(port))
+			continue
+		}
+		ranges.Range = append(ranges.Range, &mesos.Value_Range{
+			Begin: proto.Uint64(uint64(port)),
+			End:   proto.Uint64(uint64(port)),
+		})
+	}
+	return &mesos.Resource{
+		Name:   proto.String("ports"),
+		Type:   mesos.Value_RANGES.Enum(),
+		Ranges: ranges,
+	}
+}
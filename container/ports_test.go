@@ -0,0 +1,46 @@
+package container
+
+import (
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/vladimirvivien/mesos-http/mesos/mesos"
+)
+
+func portRange(begin, end uint64) *mesos.Resource {
+	return &mesos.Resource{
+		Name: proto.String("ports"),
+		Type: mesos.Value_RANGES.Enum(),
+		Ranges: &mesos.Value_Ranges{Range: []*mesos.Value_Range{
+			{Begin: proto.Uint64(begin), End: proto.Uint64(end)},
+		}},
+	}
+}
+
+func TestPortPool(t *testing.T) {
+	reserved := portRange(30000, 30009)
+	reserved.Role = proto.String("web")
+	reserved.Reservation = &mesos.Resource_ReservationInfo{Principal: proto.String("p")}
+	revocable := portRange(30010, 30019)
+	revocable.Revocable = new(mesos.Resource_RevocableInfo)
+
+	pool := NewPortPool([]*mesos.Resource{reserved, revocable, portRange(31000, 31001), portRange(31005, 31005)})
+	ports, ok := pool.Take(3)
+	if !ok {
+		t.Fatal("unable to take 3 ports")
+	}
+	if len(ports) != 3 || ports[0] != 31000 || ports[1] != 31001 || ports[2] != 31005 {
+		t.Errorf("took %v, want unreserved ports [31000 31001 31005]", ports)
+	}
+	if _, ok := pool.Take(1); ok {
+		t.Error("took a reserved or revocable port")
+	}
+
+	res := PortsResource(ports)
+	if res.GetRole() != "*" || res.Reservation != nil {
+		t.Errorf("ports resource is reserved: %v", res)
+	}
+	if got := len(res.GetRanges().GetRange()); got != 2 {
+		t.Errorf("ports resource has %d ranges, want 2", got)
+	}
+}
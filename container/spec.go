@@ -0,0 +1,107 @@
+// Package container builds and validates the ContainerInfo of tasks
+// run by the Docker or Mesos containerizer.
+package container
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Containerizers
+const (
+	Docker = "docker"
+	Mesos  = "mesos"
+)
+
+// Spec describes the container of a task
+type Spec struct {
+	Type       string
+	Image      string
+	Network    string   // host, bridge, none or user
+	Networks   []string // named networks for the user network mode
+	Volumes    []Volume
+	Ports      []Port // Docker port mappings, host ports are allocated from offers
+	Privileged bool
+	Parameters []Parameter // Docker run options
+	ForcePull  bool
+}
+
+// Volume mounts a host path into the container
+type Volume struct {
+	HostPath      string
+	ContainerPath string
+	ReadOnly      bool
+}
+
+// Port is a container port mapped to an allocated host port
+type Port struct {
+	ContainerPort uint32
+	Protocol      string
+}
+
+// Parameter is a Docker run option
+type Parameter struct {
+	Key   string
+	Value string
+}
+
+// ParseVolume parses [host_path:]container_path[:ro|rw]
+func ParseVolume(s string) (Volume, error) {
+	parts := strings.Split(s, ":")
+	var v Volume
+	if n := len(parts); n > 1 && (parts[n-1] == "ro" || parts[n-1] == "rw") {
+		v.ReadOnly = parts[n-1] == "ro"
+		parts = parts[:n-1]
+	}
+	switch len(parts) {
+	case 1:
+		v.ContainerPath = parts[0]
+	case 2:
+		v.HostPath, v.ContainerPath = parts[0], parts[1]
+	default:
+		return v, fmt.Errorf("container: invalid volume %q", s)
+	}
+	if v.ContainerPath == "" {
+		return v, fmt.Errorf("container: volume %q has no container path", s)
+	}
+	return v, nil
+}
+
+// ParsePort parses container_port[/tcp|udp]
+func ParsePort(s string) (Port, error) {
+	p := Port{Protocol: "tcp"}
+	value := s
+	if i := strings.IndexByte(s, '/'); i >= 0 {
+		value, p.Protocol = s[:i], s[i+1:]
+	}
+	port, err := strconv.ParseUint(value, 10, 16)
+	if err != nil || port == 0 {
+		return p, fmt.Errorf("container: invalid port %q", s)
+	}
+	p.ContainerPort = uint32(port)
+	if p.Protocol != "tcp" && p.Protocol != "udp" {
+		return p, fmt.Errorf("container: invalid protocol in port %q", s)
+	}
+	return p, nil
+}
+
+// ParseParameter parses key=value
+func ParseParameter(s string) (Parameter, error) {
+	i := strings.IndexByte(s, '=')
+	if i <= 0 {
+		return Parameter{}, fmt.Errorf("container: invalid parameter %q", s)
+	}
+	return Parameter{Key: s[:i], Value: s[i+1:]}, nil
+}
+
+// StringList is a flag.Value collecting strings given repeatedly
+type StringList []string
+
+func (l *StringList) String() string { return strings.Join(*l, ",") }
+
+// Set appends a value
+func (l *StringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
@@ -0,0 +1,153 @@
+package container
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/vladimirvivien/mesos-http/mesos/mesos"
+)
+
+// Validate checks a ContainerInfo against the rules the master and
+// containerizers apply, so that invalid containers are caught
+// before launch.
+func Validate(ci *mesos.ContainerInfo) error {
+	if ci.Type == nil {
+		return fmt.Errorf("container: no type")
+	}
+	for _, v := range ci.GetVolumes() {
+		if err := validateVolume(ci.GetType(), v); err != nil {
+			return err
+		}
+	}
+	for _, n := range ci.GetNetworkInfos() {
+		if n.GetName() == "" && len(n.GetIpAddresses()) == 0 {
+			return fmt.Errorf("container: network info without name or IP address")
+		}
+	}
+
+	switch ci.GetType() {
+	case mesos.ContainerInfo_DOCKER:
+		if ci.Mesos != nil {
+			return fmt.Errorf("container: docker container with mesos info")
+		}
+		return validateDocker(ci)
+	case mesos.ContainerInfo_MESOS:
+		if ci.Docker != nil {
+			return fmt.Errorf("container: mesos container with docker info")
+		}
+		if img := ci.GetMesos().GetImage(); img != nil {
+			switch img.GetType() {
+			case mesos.Image_DOCKER:
+				if img.GetDocker().GetName() == "" {
+					return fmt.Errorf("container: docker image without name")
+				}
+			case mesos.Image_APPC:
+				if img.GetAppc().GetName() == "" {
+					return fmt.Errorf("container: appc image without name")
+				}
+			default:
+				return fmt.Errorf("container: image without type")
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("container: unknown type %s", ci.GetType())
+}
+
+func validateVolume(t mesos.ContainerInfo_Type, v *mesos.Volume) error {
+	if v.Mode == nil {
+		return fmt.Errorf("container: volume %s has no mode", v.GetContainerPath())
+	}
+	if v.GetContainerPath() == "" {
+		return fmt.Errorf("container: volume without container path")
+	}
+	if v.HostPath != nil && v.Image != nil {
+		return fmt.Errorf("container: volume %s has both host path and image", v.GetContainerPath())
+	}
+	if t == mesos.ContainerInfo_DOCKER {
+		if !filepath.IsAbs(v.GetContainerPath()) {
+			return fmt.Errorf("container: docker volume %s must be absolute", v.GetContainerPath())
+		}
+		if v.Image != nil {
+			return fmt.Errorf("container: docker volume %s cannot be an image", v.GetContainerPath())
+		}
+	}
+	return nil
+}
+
+func validateDocker(ci *mesos.ContainerInfo) error {
+	docker := ci.GetDocker()
+	if docker == nil {
+		return fmt.Errorf("container: docker container without docker info")
+	}
+	if docker.GetImage() == "" {
+		return fmt.Errorf("container: docker container without image")
+	}
+
+	network := docker.GetNetwork()
+	if len(docker.GetPortMappings()) > 0 &&
+		network != mesos.ContainerInfo_DockerInfo_BRIDGE && network != mesos.ContainerInfo_DockerInfo_USER {
+		return fmt.Errorf("container: port mappings require the bridge or user network, not %s", network)
+	}
+	if network == mesos.ContainerInfo_DockerInfo_USER && len(ci.GetNetworkInfos()) != 1 {
+		return fmt.Errorf("container: user network requires exactly one named network")
+	}
+
+	seen := make(map[string]bool)
+	for _, pm := range docker.GetPortMappings() {
+		if pm.GetHostPort() == 0 || pm.GetContainerPort() == 0 {
+			return fmt.Errorf("container: port mapping with zero port")
+		}
+		protocol := pm.GetProtocol()
+		if protocol == "" {
+			protocol = "tcp"
+		}
+		if protocol != "tcp" && protocol != "udp" {
+			return fmt.Errorf("container: port mapping with protocol %s", protocol)
+		}
+		key := fmt.Sprintf("%d/%s", pm.GetHostPort(), protocol)
+		if seen[key] {
+			return fmt.Errorf("container: host port %s mapped twice", key)
+		}
+		seen[key] = true
+	}
+	for _, p := range docker.GetParameters() {
+		if p.GetKey() == "" {
+			return fmt.Errorf("container: docker parameter without key")
+		}
+	}
+	return nil
+}
+
+// ValidateTask checks the container of a task, and that the host
+// ports it maps are part of the task's ports resource.
+func ValidateTask(task *mesos.TaskInfo) error {
+	ci := task.GetContainer()
+	if ci == nil {
+		return nil
+	}
+	if err := Validate(ci); err != nil {
+		return err
+	}
+	for _, pm := range ci.GetDocker().GetPortMappings() {
+		if !hasPort(task.GetResources(), uint64(pm.GetHostPort())) {
+			return fmt.Errorf("container: host port %d is not allocated to task %s",
+				pm.GetHostPort(), task.GetTaskId().GetValue())
+		}
+	}
+	return nil
+}
+
+func hasPort(resources []*mesos.Resource, port uint64) bool {
+	for _, res := range resources {
+		if res.GetName() != "ports" {
+			continue
+		}
+		for _, r := range res.GetRanges().GetRange() {
+			if port >= r.GetBegin() && port <= r.GetEnd() {
+				return true
+			}
+		}
+	}
+	return false
+}
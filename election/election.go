@@ -0,0 +1,20 @@
+package election
+
+import "context"
+
+// Elector elects a single leader among scheduler instances.
+// Only the leader should subscribe with the master; the other
+// instances stand by until the leader's lease expires.
+type Elector interface {
+	// Campaign blocks until this instance becomes leader
+	// or the context is done.
+	Campaign(ctx context.Context) error
+
+	// Lost returns a channel that is closed when leadership,
+	// once acquired, is lost.
+	Lost() <-chan struct{}
+
+	// Resign gives up leadership so a standby can take over
+	// without waiting for the lease to expire.
+	Resign() error
+}
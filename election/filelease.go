@@ -0,0 +1,212 @@
+package election
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// lease is the content of the lease file
+type lease struct {
+	Holder  string    `json:"holder"`
+	Expires time.Time `json:"expires"`
+}
+
+// FileLease is an Elector backed by a lease file guarded with an
+// flock(2) lock on a separate lock file, so that the lease file can be
+// replaced atomically: a crash while writing the lease leaves the
+// previous one. A lease that cannot be parsed counts as expired. The
+// leader renews the lease every third of its TTL;
+// a standby takes over once the lease expires. Renewals failing with
+// an error are retried, leadership is only lost once the lease
+// expired or another instance took it. All instances must see the
+// same file, e.g. on the same host or a shared filesystem.
+type FileLease struct {
+	path string
+	id   string
+	ttl  time.Duration
+
+	mu     sync.Mutex
+	leader bool
+	lost   chan struct{}
+	stop   chan struct{}
+}
+
+// NewFileLease returns an elector using the lease file at path.
+// The id identifies this instance and must be unique.
+func NewFileLease(path, id string, ttl time.Duration) (*FileLease, error) {
+	if ttl <= 0 {
+		return nil, fmt.Errorf("election: lease TTL must be positive, got %s", ttl)
+	}
+	return &FileLease{
+		path: path,
+		id:   id,
+		ttl:  ttl,
+		lost: make(chan struct{}),
+		stop: make(chan struct{}),
+	}, nil
+}
+
+// Campaign tries to acquire the lease until it succeeds or ctx is done
+func (f *FileLease) Campaign(ctx context.Context) error {
+	ticker := time.NewTicker(f.ttl / 3)
+	defer ticker.Stop()
+	for {
+		ok, err := f.acquire()
+		if err != nil {
+			return err
+		}
+		if ok {
+			f.mu.Lock()
+			f.leader = true
+			f.mu.Unlock()
+			go f.renew(time.Now().Add(f.ttl))
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Lost returns a channel closed when the lease could not be renewed
+func (f *FileLease) Lost() <-chan struct{} {
+	return f.lost
+}
+
+// Resign stops renewing and releases the lease
+func (f *FileLease) Resign() error {
+	f.mu.Lock()
+	if !f.leader {
+		f.mu.Unlock()
+		return nil
+	}
+	f.leader = false
+	close(f.stop)
+	f.mu.Unlock()
+
+	return f.locked(func(l *lease) (*lease, error) {
+		if l.Holder != f.id {
+			return nil, nil
+		}
+		return &lease{}, nil
+	})
+}
+
+// renew extends the lease, held until expires, until it is resigned
+// or lost.
+func (f *FileLease) renew(expires time.Time) {
+	ticker := time.NewTicker(f.ttl / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-f.stop:
+			return
+		case <-time.After(time.Until(expires)):
+			log.Println("Lease expired before it could be renewed")
+		case <-ticker.C:
+			now := time.Now()
+			ok, err := f.acquire()
+			if err == nil && ok {
+				expires = now.Add(f.ttl)
+				continue
+			}
+			if err != nil {
+				log.Println("Unable to renew lease: ", err)
+				continue
+			}
+		}
+		f.mu.Lock()
+		f.leader = false
+		f.mu.Unlock()
+		close(f.lost)
+		return
+	}
+}
+
+// acquire takes or extends the lease if it is free, expired or
+// already held by this instance.
+func (f *FileLease) acquire() (bool, error) {
+	acquired := false
+	err := f.locked(func(l *lease) (*lease, error) {
+		now := time.Now()
+		if l.Holder != "" && l.Holder != f.id && now.Before(l.Expires) {
+			return nil, nil
+		}
+		acquired = true
+		return &lease{Holder: f.id, Expires: now.Add(f.ttl)}, nil
+	})
+	return acquired, err
+}
+
+// locked reads the lease under an exclusive lock and writes back
+// the lease returned by fn, unless it is nil.
+func (f *FileLease) locked(fn func(*lease) (*lease, error)) error {
+	lock, err := os.OpenFile(f.path+".lock", os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	defer lock.Close()
+	if err := syscall.Flock(int(lock.Fd()), syscall.LOCK_EX); err != nil {
+		return fmt.Errorf("election: unable to lock %s: %s", f.path, err)
+	}
+	defer syscall.Flock(int(lock.Fd()), syscall.LOCK_UN)
+
+	current := new(lease)
+	data, err := ioutil.ReadFile(f.path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, current); err != nil {
+			log.Println("Ignoring corrupt lease ", f.path, ": ", err)
+			current = new(lease)
+		}
+	}
+
+	next, err := fn(current)
+	if err != nil || next == nil {
+		return err
+	}
+	data, err = json.Marshal(next)
+	if err != nil {
+		return err
+	}
+	return f.write(data)
+}
+
+// write replaces the lease file with a synced temporary file
+func (f *FileLease) write(data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(f.path), "."+filepath.Base(f.path))
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), f.path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package election
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const ttl = 150 * time.Millisecond
+
+func leasePath(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "lease")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return filepath.Join(dir, "lease")
+}
+
+func newLease(t *testing.T, path, id string) *FileLease {
+	f, err := NewFileLease(path, id, ttl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func campaign(t *testing.T, f *FileLease, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return f.Campaign(ctx)
+}
+
+func TestNewFileLease(t *testing.T) {
+	for _, d := range []time.Duration{0, -time.Second} {
+		if _, err := NewFileLease("lease", "a", d); err == nil {
+			t.Errorf("TTL %s accepted", d)
+		}
+	}
+}
+
+func TestSingleLeader(t *testing.T) {
+	path := leasePath(t)
+	a, b := newLease(t, path, "a"), newLease(t, path, "b")
+	if err := campaign(t, a, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	defer a.Resign()
+
+	// b stands by while a renews its lease
+	if err := campaign(t, b, 3*ttl); err != context.DeadlineExceeded {
+		t.Fatalf("standby campaign returned %v", err)
+	}
+	select {
+	case <-a.Lost():
+		t.Fatal("leader lost a renewed lease")
+	default:
+	}
+}
+
+func TestResign(t *testing.T) {
+	path := leasePath(t)
+	a, b := newLease(t, path, "a"), newLease(t, path, "b")
+	if err := campaign(t, a, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if err := a.Resign(); err != nil {
+		t.Fatal(err)
+	}
+	// the standby takes over without waiting for the lease to expire
+	if err := campaign(t, b, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Resign()
+	if elapsed := time.Since(start); elapsed >= ttl {
+		t.Errorf("standby elected after %s", elapsed)
+	}
+	select {
+	case <-a.Lost():
+		t.Fatal("resigned leader reported lost")
+	default:
+	}
+}
+
+func TestRenewErrors(t *testing.T) {
+	path := leasePath(t)
+	a := newLease(t, path, "a")
+	if err := campaign(t, a, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	defer a.Resign()
+
+	// renewals fail while the lease file cannot be replaced; a short
+	// outage does not lose the lease.
+	saved := path + ".saved"
+	if err := os.Rename(path, saved); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(path, 0700); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(ttl / 2)
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(saved, path); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-a.Lost():
+		t.Fatal("lease lost on a transient error")
+	case <-time.After(2 * ttl):
+	}
+
+	// failing until the lease expires loses it
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(path, 0700); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-a.Lost():
+	case <-time.After(3 * ttl):
+		t.Fatal("lease not lost once expired")
+	}
+}
+
+func TestCorruptLease(t *testing.T) {
+	path := leasePath(t)
+	if err := ioutil.WriteFile(path, []byte(`{"holder": "b", "exp`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	// a torn lease counts as expired
+	a := newLease(t, path, "a")
+	if err := campaign(t, a, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	defer a.Resign()
+	err := a.locked(func(l *lease) (*lease, error) {
+		if l.Holder != "a" {
+			t.Errorf("lease held by %q, want a", l.Holder)
+		}
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTakenOver(t *testing.T) {
+	path := leasePath(t)
+	a := newLease(t, path, "a")
+	if err := campaign(t, a, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	defer a.Resign()
+
+	// another instance holds the lease
+	err := a.locked(func(l *lease) (*lease, error) {
+		return &lease{Holder: "b", Expires: time.Now().Add(time.Hour)}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-a.Lost():
+	case <-time.After(2 * ttl):
+		t.Fatal("lease taken over but not lost")
+	}
+}
@@ -0,0 +1,145 @@
+package eventlog
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/vladimirvivien/mesos-http/recordio"
+)
+
+// Kind tells whether an entry is a received event, a sent call,
+// the session settings a replay needs, or a task submitted to or
+// cancelled in the queue of the scheduler
+type Kind byte
+
+const (
+	Event   Kind = 'E'
+	Call    Kind = 'C'
+	Session Kind = 'S'
+	Submit  Kind = 'T'
+	Cancel  Kind = 'X'
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Event:
+		return "EVENT"
+	case Call:
+		return "CALL"
+	case Session:
+		return "SESSION"
+	case Submit:
+		return "SUBMIT"
+	case Cancel:
+		return "CANCEL"
+	}
+	return fmt.Sprintf("Kind(%d)", byte(k))
+}
+
+// headerSize is the kind byte plus the timestamp in nanoseconds
+const headerSize = 9
+
+// Entry is a recorded event or call. Data holds the bytes exactly
+// as they went over the wire: the RecordIO payload of an event and
+// the protobuf encoding of a call.
+type Entry struct {
+	Kind Kind
+	Time time.Time
+	Data []byte
+}
+
+// Writer appends entries to a log, each one a RecordIO record
+type Writer struct {
+	mu sync.Mutex
+	w  io.Writer
+	c  io.Closer
+}
+
+// Create creates, or truncates, the log file at path
+func Create(path string) (*Writer, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Writer{w: f, c: f}, nil
+}
+
+// NewWriter returns a writer appending to w
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Write appends an entry stamped with the current time
+func (w *Writer) Write(kind Kind, data []byte) error {
+	record := make([]byte, headerSize+len(data))
+	record[0] = byte(kind)
+	binary.BigEndian.PutUint64(record[1:headerSize], uint64(time.Now().UnixNano()))
+	copy(record[headerSize:], data)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return recordio.Write(w.w, record)
+}
+
+// Close closes the underlying file, if any
+func (w *Writer) Close() error {
+	if w.c == nil {
+		return nil
+	}
+	return w.c.Close()
+}
+
+// Reader reads the entries of a log in order
+type Reader struct {
+	r *recordio.Reader
+	c io.Closer
+}
+
+// Open opens the log file at path
+func Open(path string) (*Reader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{r: recordio.NewReader(f), c: f}, nil
+}
+
+// NewReader returns a reader of the log in r
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: recordio.NewReader(r)}
+}
+
+// Next returns the next entry or io.EOF at the end of the log
+func (r *Reader) Next() (*Entry, error) {
+	record, err := r.r.Read()
+	if err != nil {
+		return nil, err
+	}
+	if len(record) < headerSize {
+		return nil, fmt.Errorf("eventlog: short entry of %d bytes", len(record))
+	}
+	kind := Kind(record[0])
+	switch kind {
+	case Event, Call, Session, Submit, Cancel:
+	default:
+		return nil, fmt.Errorf("eventlog: unknown entry kind %q", record[0])
+	}
+	nanos := int64(binary.BigEndian.Uint64(record[1:headerSize]))
+	return &Entry{
+		Kind: kind,
+		Time: time.Unix(0, nanos),
+		Data: record[headerSize:],
+	}, nil
+}
+
+// Close closes the underlying file, if any
+func (r *Reader) Close() error {
+	if r.c == nil {
+		return nil
+	}
+	return r.c.Close()
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/vladimirvivien/mesos-http/message"
+)
+
+// setting applies the value of a tunable executor setting; it is
+// called with e.mu held.
+type setting func(e *executor, v string) error
+
+// settings are the executor settings read from the environment at
+// launch, which the scheduler may also change with a config reload.
+var settings = map[string]setting{
+	"MESOS_RECOVERY_TIMEOUT": func(e *executor, v string) error {
+		return setDuration(&e.recoveryTimeout, v)
+	},
+	"MESOS_SUBSCRIPTION_BACKOFF_MAX": func(e *executor, v string) error {
+		return setDuration(&e.backoffMax, v)
+	},
+	"MESOS_EXECUTOR_SHUTDOWN_GRACE_PERIOD": func(e *executor, v string) error {
+		return setDuration(&e.shutdownGrace, v)
+	},
+	"EXECUTOR_USAGE_INTERVAL": func(e *executor, v string) error {
+		return setDuration(&e.usageInterval, v)
+	},
+	"EXECUTOR_LOG_MAX_SIZE": func(e *executor, v string) error {
+		size, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || size <= 0 {
+			return fmt.Errorf("invalid size %s", v)
+		}
+		e.logMaxSize = size
+		return nil
+	},
+	"EXECUTOR_LOG_MAX_FILES": func(e *executor, v string) error {
+		files, err := strconv.Atoi(v)
+		if err != nil || files < 0 {
+			return fmt.Errorf("invalid file count %s", v)
+		}
+		e.logMaxFiles = files
+		return nil
+	},
+}
+
+func setDuration(d *time.Duration, v string) error {
+	parsed, err := parseDuration(v)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// configure applies the settings present in the environment
+func (e *executor) configure() error {
+	values := make(map[string]string)
+	for name := range settings {
+		if v := os.Getenv(name); v != "" {
+			values[name] = v
+		}
+	}
+	_, err := e.apply(values)
+	return err
+}
+
+// apply validates all values before applying any of them and
+// returns the names of the applied settings.
+func (e *executor) apply(values map[string]string) ([]string, error) {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		if _, ok := settings[name]; !ok {
+			return nil, fmt.Errorf("Unknown setting %s", name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	// apply to a copy first, so an invalid value changes nothing
+	trial := &executor{
+		recoveryTimeout: e.recoveryTimeout,
+		backoffMax:      e.backoffMax,
+		shutdownGrace:   e.shutdownGrace,
+		logMaxSize:      e.logMaxSize,
+		logMaxFiles:     e.logMaxFiles,
+		usageInterval:   e.usageInterval,
+	}
+	for _, name := range names {
+		if err := settings[name](trial, values[name]); err != nil {
+			return nil, fmt.Errorf("Invalid %s: %s", name, err)
+		}
+	}
+	e.recoveryTimeout = trial.recoveryTimeout
+	e.backoffMax = trial.backoffMax
+	e.shutdownGrace = trial.shutdownGrace
+	e.logMaxSize = trial.logMaxSize
+	e.logMaxFiles = trial.logMaxFiles
+	e.usageInterval = trial.usageInterval
+	return names, nil
+}
+
+// reload handles a config reload pushed by the scheduler
+func (e *executor) reload(msg *message.Message) (interface{}, error) {
+	req := new(message.ConfigReload)
+	if err := msg.Decode(req); err != nil {
+		return nil, err
+	}
+	applied, err := e.apply(req.Settings)
+	if err != nil {
+		return nil, err
+	}
+	log.Println("Reloaded settings ", applied)
+	return &message.ConfigReloaded{Applied: applied}, nil
+}
@@ -5,20 +5,61 @@ import (
 	"fmt"
 	"io"
 	"log"
-	"math/rand"
 	"net/http"
 	"os"
+	"sync"
+	"time"
 
 	"github.com/gogo/protobuf/proto"
 	"github.com/vladimirvivien/mesos-http/client"
+	"github.com/vladimirvivien/mesos-http/fetch"
 	exec "github.com/vladimirvivien/mesos-http/mesos/exec"
 	"github.com/vladimirvivien/mesos-http/mesos/mesos"
+	"github.com/vladimirvivien/mesos-http/message"
+	"github.com/vladimirvivien/mesos-http/recordio"
+	"github.com/vladimirvivien/mesos-http/tasklog"
+	"github.com/vladimirvivien/mesos-http/uuid"
 )
 
 // Scheduler represents a Mesos scheduler
 type executor struct {
 	id          *mesos.ExecutorID
 	frameworkID *mesos.FrameworkID
+	framework   *mesos.FrameworkInfo
+
+	tasks map[string]*task
+	mu    sync.Mutex
+
+	// launched tasks and updates not yet acknowledged, resent to
+	// the agent when resubscribing. The first update of each task
+	// is in flight, the others wait for it to be acknowledged.
+	unackedTasks map[string]*mesos.TaskInfo
+	updates      map[string][]*exec.Call_Update
+	updateRetry  time.Duration
+
+	checkpoint      bool
+	recoveryTimeout time.Duration
+	backoffMax      time.Duration
+
+	stream       *http.Response
+	shuttingDown bool
+
+	// terminating is set once SHUTDOWN is received; running counts
+	// the launched tasks not yet reported terminal.
+	terminating   bool
+	running       sync.WaitGroup
+	shutdownGrace time.Duration
+
+	sandbox     string
+	logMaxSize  int64
+	logMaxFiles int
+
+	// resource usage of each task is reported at this interval,
+	// not at all if zero
+	usageInterval time.Duration
+
+	fetcher  *fetch.Fetcher
+	messages *message.Endpoint
 
 	client   *client.Client
 	events   chan *exec.Event
@@ -26,23 +67,49 @@ type executor struct {
 }
 
 func newExec(agent string) *executor {
-	return &executor{
-		client:   client.New(agent, "/api/v1/executor"),
-		events:   make(chan *exec.Event),
-		doneChan: make(chan struct{}),
+	e := &executor{
+		client: client.New(agent, "/api/v1/executor"),
+		tasks:  make(map[string]*task),
+		events: make(chan *exec.Event),
+
+		unackedTasks:    make(map[string]*mesos.TaskInfo),
+		updates:         make(map[string][]*exec.Call_Update),
+		updateRetry:     updateRetryInterval,
+		recoveryTimeout: 15 * time.Minute,
+		backoffMax:      2 * time.Second,
+		shutdownGrace:   5 * time.Second,
+		logMaxSize:      10 * 1024 * 1024,
+		logMaxFiles:     5,
+		usageInterval:   10 * time.Second,
+		doneChan:        make(chan struct{}),
 	}
+	e.messages = message.NewEndpoint(func(_ message.Address, data []byte) error {
+		return e.sendMessage(data)
+	}, message.JSON)
+	e.messages.Handle(tasklog.TailType, e.tail)
+	e.messages.Handle(message.ConfigReloadType, e.reload)
+	return e
 }
 
 func (e *executor) start() <-chan struct{} {
-	if err := e.subscribe(); err != nil {
+	resp, err := e.subscribe()
+	if err != nil {
 		log.Fatal(err)
 	}
+	go e.run(resp)
 	go e.handleEvents()
 	return e.doneChan
 }
 
+// stop closes the event stream; the executor exits once the
+// remaining events are handled.
 func (e *executor) stop() {
-	close(e.events)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.shuttingDown = true
+	if e.stream != nil {
+		e.stream.Body.Close()
+	}
 }
 
 func (e *executor) send(call *exec.Call) (*http.Response, error) {
@@ -53,43 +120,57 @@ func (e *executor) send(call *exec.Call) (*http.Response, error) {
 	return e.client.Send(payload)
 }
 
-func (e *executor) subscribe() error {
+// subscribe subscribes with the agent, passing the tasks and updates
+// not yet acknowledged, and returns the event stream response.
+func (e *executor) subscribe() (*http.Response, error) {
+	e.mu.Lock()
+	tasks := make([]*mesos.TaskInfo, 0, len(e.unackedTasks))
+	for _, task := range e.unackedTasks {
+		tasks = append(tasks, task)
+	}
+	e.mu.Unlock()
+	updates := e.unacked()
+
 	call := &exec.Call{
 		FrameworkId: e.frameworkID,
 		ExecutorId:  e.id,
 		Type:        exec.Call_SUBSCRIBE.Enum(),
 		Subscribe: &exec.Call_Subscribe{
-			UnacknowledgedTasks:   []*mesos.TaskInfo{},
-			UnacknowledgedUpdates: []*exec.Call_Update{},
+			UnacknowledgedTasks:   tasks,
+			UnacknowledgedUpdates: updates,
 		},
 	}
 
 	resp, err := e.send(call)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("Subscribe with unexpected response status: %d", resp.StatusCode)
+		resp.Body.Close()
+		return nil, fmt.Errorf("Subscribe with unexpected response status: %d", resp.StatusCode)
 	}
 	log.Println("Mesos-Stream-Id:", e.client.StreamID)
 
-	go e.qEvents(resp)
-
-	return nil
+	e.mu.Lock()
+	e.stream = resp
+	e.mu.Unlock()
+	return resp, nil
 }
 
 func (e *executor) qEvents(resp *http.Response) {
-	defer func() {
-		resp.Body.Close()
-		close(e.events)
-	}()
-	dec := json.NewDecoder(resp.Body)
+	defer resp.Body.Close()
+	reader := recordio.NewReader(resp.Body)
 	for {
-		event := new(exec.Event)
-		if err := dec.Decode(event); err != nil {
-			if err == io.EOF {
-				return
+		data, err := reader.Read()
+		if err != nil {
+			if err != io.EOF && !e.stopping() {
+				log.Println("Event stream failed: ", err)
 			}
+			return
+		}
+		event := new(exec.Event)
+		if err := json.Unmarshal(data, event); err != nil {
+			log.Println("Unable to decode event: ", err)
 			continue
 		}
 		e.events <- event
@@ -104,35 +185,44 @@ func (e *executor) handleEvents() {
 		case exec.Event_SUBSCRIBED:
 			sub := ev.GetSubscribed()
 			log.Println("Executor subscribed with id", sub.GetExecutorInfo().GetExecutorId())
+			e.framework = sub.GetFrameworkInfo()
 
 		case exec.Event_LAUNCH:
-			task := ev.GetLaunch().GetTask()
-			log.Println("Launching task: ", task.GetTaskId().GetValue())
+			info := ev.GetLaunch().GetTask()
+			log.Println("Launching task: ", info.GetTaskId().GetValue())
 
-			err := e.sendUpdate(task, mesos.TaskState_TASK_RUNNING.Enum())
+			// tasks run concurrently, without blocking the event loop,
+			// once registered for the kills that may follow
+			t, err := e.register(info)
 			if err != nil {
-				log.Fatal("Failed while sending update:", err)
+				log.Println("Failed to launch task ", info.GetTaskId().GetValue(), ": ", err)
+				break
 			}
-
-			// do work here...
-
-			err = e.sendUpdate(task, mesos.TaskState_TASK_FINISHED.Enum())
-			if err != nil {
-				log.Fatal("Failed while sending update:", err)
+			if t != nil {
+				go func() {
+					if err := e.launch(t); err != nil {
+						log.Println("Failed to launch task ", t.info.GetTaskId().GetValue(), ": ", err)
+					}
+				}()
 			}
 
 		case exec.Event_ACKNOWLEDGED:
 			log.Println("ACK received:", ev.GetAcknowledged().String())
+			e.acknowledged(ev.GetAcknowledged())
 
 		case exec.Event_MESSAGE:
-			log.Println("Message Received:", ev.GetMessage().String())
+			if err := e.messages.Receive(message.Address{}, ev.GetMessage().GetData()); err != nil {
+				log.Println("Unable to handle message: ", err)
+			}
 
 		case exec.Event_KILL:
-			log.Println("Received request to kill executor")
+			taskID := ev.GetKill().GetTaskId().GetValue()
+			log.Println("Received request to kill task ", taskID)
+			go e.kill(taskID, 0)
 
 		case exec.Event_SHUTDOWN:
 			log.Println("Received shutdown request.  Shutting down...")
-			e.stop()
+			go e.shutdown()
 
 		case exec.Event_ERROR:
 			err := ev.GetError().GetMessage()
@@ -141,40 +231,23 @@ func (e *executor) handleEvents() {
 	}
 }
 
-func (e *executor) sendUpdate(task *mesos.TaskInfo, state *mesos.TaskState) error {
-	call := &exec.Call{
-		Type:        exec.Call_UPDATE.Enum(),
-		FrameworkId: e.frameworkID,
-		ExecutorId:  e.id,
-		Update: &exec.Call_Update{
-			Status: &mesos.TaskStatus{
-				TaskId:     task.TaskId,
-				ExecutorId: e.id,
-				State:      state,
-				Source:     mesos.TaskStatus_SOURCE_EXECUTOR.Enum(),
-				Uuid:       e.getUUID(),
-			},
-		},
-	}
-
-	resp, err := e.send(call)
-	if err != nil {
-		return err
-	}
-	if resp.StatusCode != http.StatusAccepted {
-		return fmt.Errorf("Update return unexpected response status: %d", resp.StatusCode)
-	}
-
-	return nil
+func (e *executor) sendUpdate(task *mesos.TaskInfo, state *mesos.TaskState, message string) error {
+	return e.sendStatus(e.newStatus(task, state, message))
 }
 
-// simple Uuid generator, illustrative only
-func (e *executor) getUUID() []byte {
-	bytes := make([]byte, 16)
-	for i := 0; i < 16; i++ {
-		bytes[i] = byte(rand.Int31n(256))
+// newStatus returns a status update of the task sent by the executor
+func (e *executor) newStatus(task *mesos.TaskInfo, state *mesos.TaskState, message string) *mesos.TaskStatus {
+	status := &mesos.TaskStatus{
+		TaskId:     task.TaskId,
+		ExecutorId: e.id,
+		State:      state,
+		Source:     mesos.TaskStatus_SOURCE_EXECUTOR.Enum(),
+		Uuid:       uuid.New().Bytes(),
+	}
+	if message != "" {
+		status.Message = proto.String(message)
 	}
-	return bytes
+	return status
 }
 
 func main() {
@@ -198,5 +271,11 @@ func main() {
 	exec.id = &mesos.ExecutorID{
 		Value: proto.String(execid),
 	}
+	if err := exec.configure(); err != nil {
+		log.Fatal(err)
+	}
+	exec.configureRecovery()
+	exec.configureLogs()
+	exec.configureFetcher()
 	<-exec.start()
 }
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"net/http"
+	osexec "os/exec"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	exec "github.com/vladimirvivien/mesos-http/mesos/exec"
+	"github.com/vladimirvivien/mesos-http/mesos/mesos"
+	"github.com/vladimirvivien/mesos-http/mesostest/exectest"
+)
+
+const timeout = 5 * time.Second
+
+// startExec runs an executor subscribed with a fake agent. Both are
+// shut down when the test ends.
+func startExec(t *testing.T, autoAck bool, configure func(e *executor)) (*exectest.Agent, *executor) {
+	a := exectest.NewAgent("fw", "ex")
+	a.AutoAck = autoAck
+	e := newExec(a.Addr())
+	e.frameworkID = a.FrameworkID
+	e.id = a.ExecutorID
+	e.usageInterval = 0
+	e.shutdownGrace = time.Second
+	if configure != nil {
+		configure(e)
+	}
+	done := e.start()
+	t.Cleanup(func() {
+		if a.Shutdown() == nil {
+			select {
+			case <-done:
+			case <-time.After(timeout):
+				t.Error("executor did not stop")
+			}
+		}
+		a.Close()
+		for _, err := range a.Errors() {
+			t.Error(err)
+		}
+	})
+	if err := a.WaitForSubscribe(1, timeout); err != nil {
+		t.Fatal(err)
+	}
+	return a, e
+}
+
+func shellTask(a *exectest.Agent, id, command string) *mesos.TaskInfo {
+	return &mesos.TaskInfo{
+		Name:    proto.String(id),
+		TaskId:  &mesos.TaskID{Value: proto.String(id)},
+		AgentId: a.AgentID,
+		Command: &mesos.CommandInfo{
+			Shell: proto.Bool(true),
+			Value: proto.String(command),
+		},
+	}
+}
+
+func waitUpdate(t *testing.T, a *exectest.Agent, taskID string, state mesos.TaskState) *mesos.TaskStatus {
+	status, err := a.WaitForUpdate(taskID, state, timeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return status
+}
+
+func TestLaunch(t *testing.T) {
+	a, _ := startExec(t, true, nil)
+	if err := a.Launch(shellTask(a, "ok", "true")); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Launch(shellTask(a, "failed", "exit 3")); err != nil {
+		t.Fatal(err)
+	}
+	waitUpdate(t, a, "ok", mesos.TaskState_TASK_RUNNING)
+	waitUpdate(t, a, "ok", mesos.TaskState_TASK_FINISHED)
+	waitUpdate(t, a, "failed", mesos.TaskState_TASK_RUNNING)
+	status := waitUpdate(t, a, "failed", mesos.TaskState_TASK_FAILED)
+	if got, want := status.GetMessage(), "Command exited with status 3"; got != want {
+		t.Errorf("failure message %q, want %q", got, want)
+	}
+}
+
+func TestLaunchCommandData(t *testing.T) {
+	a, _ := startExec(t, true, nil)
+	data, err := proto.Marshal(&mesos.CommandInfo{
+		Shell: proto.Bool(true),
+		Value: proto.String("true"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	task := &mesos.TaskInfo{
+		Name:    proto.String("data"),
+		TaskId:  &mesos.TaskID{Value: proto.String("data")},
+		AgentId: a.AgentID,
+		Data:    data,
+	}
+	if err := a.Launch(task); err != nil {
+		t.Fatal(err)
+	}
+	waitUpdate(t, a, "data", mesos.TaskState_TASK_FINISHED)
+
+	// undecodable data fails the task
+	task = &mesos.TaskInfo{
+		Name:    proto.String("bad"),
+		TaskId:  &mesos.TaskID{Value: proto.String("bad")},
+		AgentId: a.AgentID,
+		Data:    []byte{0xff},
+	}
+	if err := a.Launch(task); err != nil {
+		t.Fatal(err)
+	}
+	waitUpdate(t, a, "bad", mesos.TaskState_TASK_FAILED)
+}
+
+func TestKill(t *testing.T) {
+	a, _ := startExec(t, true, nil)
+	if err := a.Launch(shellTask(a, "sleep", "sleep 30")); err != nil {
+		t.Fatal(err)
+	}
+	waitUpdate(t, a, "sleep", mesos.TaskState_TASK_RUNNING)
+	if err := a.Kill("sleep"); err != nil {
+		t.Fatal(err)
+	}
+	waitUpdate(t, a, "sleep", mesos.TaskState_TASK_KILLED)
+}
+
+func TestUpdatesAwaitAcknowledgement(t *testing.T) {
+	a, _ := startExec(t, false, nil)
+	if err := a.Launch(shellTask(a, "t", "true")); err != nil {
+		t.Fatal(err)
+	}
+	running := waitUpdate(t, a, "t", mesos.TaskState_TASK_RUNNING)
+
+	// the terminal update waits for RUNNING to be acknowledged
+	if _, err := a.WaitForUpdate("t", mesos.TaskState_TASK_FINISHED, 300*time.Millisecond); err == nil {
+		t.Fatal("update sent before the previous one was acknowledged")
+	}
+	if err := a.Ack(running.TaskId, running.Uuid); err != nil {
+		t.Fatal(err)
+	}
+	finished := waitUpdate(t, a, "t", mesos.TaskState_TASK_FINISHED)
+	if err := a.Ack(finished.TaskId, finished.Uuid); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOneUpdateInFlightPerTask(t *testing.T) {
+	a, _ := startExec(t, false, nil)
+	for _, id := range []string{"t1", "t2"} {
+		if err := a.Launch(shellTask(a, id, "true")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r1 := waitUpdate(t, a, "t1", mesos.TaskState_TASK_RUNNING)
+	r2 := waitUpdate(t, a, "t2", mesos.TaskState_TASK_RUNNING)
+
+	// an ack only releases the next update of its task
+	if err := a.Ack(r1.TaskId, r1.Uuid); err != nil {
+		t.Fatal(err)
+	}
+	f1 := waitUpdate(t, a, "t1", mesos.TaskState_TASK_FINISHED)
+	if _, err := a.WaitForUpdate("t2", mesos.TaskState_TASK_FINISHED, 300*time.Millisecond); err == nil {
+		t.Fatal("update of t2 sent before the previous one was acknowledged")
+	}
+	if err := a.Ack(r2.TaskId, r2.Uuid); err != nil {
+		t.Fatal(err)
+	}
+	f2 := waitUpdate(t, a, "t2", mesos.TaskState_TASK_FINISHED)
+	for _, f := range []*mesos.TaskStatus{f1, f2} {
+		if err := a.Ack(f.TaskId, f.Uuid); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(a.Updates()); n != 4 {
+		t.Errorf("%d updates sent, want 4", n)
+	}
+}
+
+func TestUpdateResentWithSameUUID(t *testing.T) {
+	a, _ := startExec(t, true, func(e *executor) {
+		e.updateRetry = 50 * time.Millisecond
+	})
+	a.Reject(exec.Call_UPDATE, http.StatusServiceUnavailable)
+	if err := a.Launch(shellTask(a, "t", "sleep 30")); err != nil {
+		t.Fatal(err)
+	}
+	var rejected []*mesos.TaskStatus
+	deadline := time.Now().Add(timeout)
+	for len(rejected) < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d updates rejected, want them resent", len(rejected))
+		}
+		time.Sleep(10 * time.Millisecond)
+		rejected = rejected[:0]
+		for _, call := range a.Calls() {
+			if call.GetType() == exec.Call_UPDATE {
+				rejected = append(rejected, call.GetUpdate().GetStatus())
+			}
+		}
+	}
+
+	a.Reject(exec.Call_UPDATE, 0)
+	running := waitUpdate(t, a, "t", mesos.TaskState_TASK_RUNNING)
+	for _, status := range append(rejected, running) {
+		if status.GetState() != mesos.TaskState_TASK_RUNNING || string(status.GetUuid()) != string(running.GetUuid()) {
+			t.Errorf("sent %s update %x, want RUNNING %x", status.GetState(), status.GetUuid(), running.GetUuid())
+		}
+	}
+	if err := a.Kill("t"); err != nil {
+		t.Fatal(err)
+	}
+	waitUpdate(t, a, "t", mesos.TaskState_TASK_KILLED)
+}
+
+func TestShutdown(t *testing.T) {
+	a, e := startExec(t, true, nil)
+	if err := a.Launch(shellTask(a, "sleep", "sleep 30")); err != nil {
+		t.Fatal(err)
+	}
+	waitUpdate(t, a, "sleep", mesos.TaskState_TASK_RUNNING)
+	if err := a.Shutdown(); err != nil {
+		t.Fatal(err)
+	}
+	waitUpdate(t, a, "sleep", mesos.TaskState_TASK_KILLED)
+	select {
+	case <-e.doneChan:
+	case <-time.After(timeout):
+		t.Fatal("executor did not stop on shutdown")
+	}
+}
+
+func TestRecovery(t *testing.T) {
+	a, _ := startExec(t, false, func(e *executor) {
+		e.checkpoint = true
+		e.backoffMax = 100 * time.Millisecond
+	})
+	if err := a.Launch(shellTask(a, "sleep", "sleep 30")); err != nil {
+		t.Fatal(err)
+	}
+	running := waitUpdate(t, a, "sleep", mesos.TaskState_TASK_RUNNING)
+
+	// the executor resubscribes once the agent is back, passing
+	// the task and update not yet acknowledged
+	a.Restart(200 * time.Millisecond)
+	if err := a.WaitForSubscribe(2, timeout); err != nil {
+		t.Fatal(err)
+	}
+	sub := a.Subscriptions()[1]
+	if tasks := sub.GetUnacknowledgedTasks(); len(tasks) != 1 || tasks[0].GetTaskId().GetValue() != "sleep" {
+		t.Errorf("unacknowledged tasks %v, want sleep", tasks)
+	}
+	updates := sub.GetUnacknowledgedUpdates()
+	if len(updates) != 1 || string(updates[0].GetStatus().GetUuid()) != string(running.GetUuid()) {
+		t.Errorf("unacknowledged updates %v, want the RUNNING update", updates)
+	}
+
+	if err := a.Ack(running.TaskId, running.Uuid); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Kill("sleep"); err != nil {
+		t.Fatal(err)
+	}
+	waitUpdate(t, a, "sleep", mesos.TaskState_TASK_KILLED)
+}
+
+func TestKillReaped(t *testing.T) {
+	a, e := startExec(t, true, nil)
+	reaped := make(chan struct{})
+	close(reaped)
+	// the process is reaped: its group is not signaled
+	tk := &task{
+		info:    shellTask(a, "gone", "true"),
+		cmd:     osexec.Command("true"),
+		state:   mesos.TaskState_TASK_RUNNING,
+		started: reaped,
+		done:    reaped,
+	}
+	e.mu.Lock()
+	e.tasks["gone"] = tk
+	e.mu.Unlock()
+	e.kill("gone", 0)
+	e.mu.Lock()
+	delete(e.tasks, "gone")
+	e.mu.Unlock()
+
+	// nor is any update sent after the terminal one
+	tk.state = mesos.TaskState_TASK_KILLED
+	if err := e.report(tk, mesos.TaskState_TASK_KILLING, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.WaitForUpdate("gone", mesos.TaskState_TASK_KILLING, 300*time.Millisecond); err == nil {
+		t.Error("KILLING reported after the task terminated")
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/vladimirvivien/mesos-http/fetch"
+	"github.com/vladimirvivien/mesos-http/mesos/mesos"
+)
+
+// configureFetcher sets the cache of fetched URIs, shared by the
+// executors of an agent, from EXECUTOR_FETCHER_CACHE_DIR.
+func (e *executor) configureFetcher() {
+	dir := os.Getenv("EXECUTOR_FETCHER_CACHE_DIR")
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "mesos-http-fetcher")
+	}
+	e.fetcher = &fetch.Fetcher{CacheDir: dir}
+}
+
+// fetch downloads the URIs of a task command into the sandbox, or
+// the working directory without one, where the task runs. It
+// returns a summary of the fetches for the task status message.
+func (e *executor) fetch(uris []*mesos.CommandInfo_URI) (string, error) {
+	if len(uris) == 0 {
+		return "", nil
+	}
+	dir := e.sandbox
+	if dir == "" {
+		dir = "."
+	}
+	results, err := e.fetcher.FetchAll(uris, dir)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(results, "; "), nil
+}
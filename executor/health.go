@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/vladimirvivien/mesos-http/mesos/mesos"
+)
+
+// defaults applied to non-positive health check settings, which
+// would otherwise check continuously or time out at once
+const (
+	defaultHealthInterval = 10 * time.Second
+	defaultHealthTimeout  = 20 * time.Second
+)
+
+// checkHealth runs the health check of a task until the task
+// terminates. Every change of health is reported as a TASK_RUNNING
+// update with TaskStatus.healthy set. Failures within the grace
+// period after launch are ignored, until the task is first healthy;
+// after consecutive_failures failures the task is killed and later
+// reported unhealthy.
+func (e *executor) checkHealth(t *task) {
+	hc := t.info.GetHealthCheck()
+	taskID := t.info.GetTaskId().GetValue()
+	launched := time.Now()
+	grace := seconds(hc.GetGracePeriodSeconds())
+	interval := seconds(hc.GetIntervalSeconds())
+	if interval <= 0 {
+		interval = defaultHealthInterval
+	}
+	timeout := seconds(hc.GetTimeoutSeconds())
+	if timeout <= 0 {
+		timeout = defaultHealthTimeout
+	}
+
+	select {
+	case <-t.done:
+		return
+	case <-time.After(seconds(hc.GetDelaySeconds())):
+	}
+
+	var healthy *bool
+	var failures uint32
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		err := runCheck(e, hc, timeout)
+		if e.isKilling(t) {
+			return
+		}
+
+		if err == nil {
+			failures = 0
+			if healthy == nil || !*healthy {
+				log.Println("Task ", taskID, " is healthy")
+				healthy = proto.Bool(true)
+				e.reportHealth(t, true, "")
+			}
+		} else if healthy != nil || time.Since(launched) >= grace {
+			failures++
+			log.Println("Health check of task ", taskID, " failed (", failures, "): ", err)
+			if healthy == nil || *healthy {
+				healthy = proto.Bool(false)
+				e.reportHealth(t, false, err.Error())
+			}
+			if failures >= hc.GetConsecutiveFailures() {
+				log.Println("Killing task ", taskID, " after ", failures, " failed health checks")
+				e.mu.Lock()
+				t.unhealthy = !t.killing
+				e.mu.Unlock()
+				e.kill(taskID, 0)
+				return
+			}
+		}
+
+		select {
+		case <-t.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reportHealth sends a TASK_RUNNING update carrying the task health,
+// unless the task is being killed or terminated meanwhile.
+func (e *executor) reportHealth(t *task, healthy bool, message string) {
+	status := e.newStatus(t.info, mesos.TaskState_TASK_RUNNING.Enum(), message)
+	status.Healthy = proto.Bool(healthy)
+	if err := e.sendRunning(t, status); err != nil {
+		log.Println("Failed while sending update:", err)
+	}
+}
+
+func (e *executor) isKilling(t *task) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return t.killing
+}
+
+// runCheck runs a single HTTP or command health check
+func runCheck(e *executor, hc *mesos.HealthCheck, timeout time.Duration) error {
+	switch {
+	case hc.GetHttp() != nil:
+		return checkHTTP(hc.GetHttp(), timeout)
+	case hc.GetCommand() != nil:
+		return e.checkCommand(hc.GetCommand(), timeout)
+	}
+	return fmt.Errorf("Health check has neither http nor command")
+}
+
+// checkHTTP sends a GET request to the task on localhost. Any
+// response status is healthy unless statuses are specified.
+func checkHTTP(check *mesos.HealthCheck_HTTP, timeout time.Duration) error {
+	url := fmt.Sprintf("http://127.0.0.1:%d%s", check.GetPort(), check.GetPath())
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if len(check.GetStatuses()) == 0 {
+		return nil
+	}
+	for _, status := range check.GetStatuses() {
+		if int(status) == resp.StatusCode {
+			return nil
+		}
+	}
+	return fmt.Errorf("Unexpected status %d from %s", resp.StatusCode, url)
+}
+
+// checkCommand runs the check command; a zero exit status is healthy
+func (e *executor) checkCommand(info *mesos.CommandInfo, timeout time.Duration) error {
+	cmd, err := e.buildCmd(info)
+	if err != nil {
+		return err
+	}
+	cmd.Stdout, cmd.Stderr = nil, nil
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("%s", exitMessage(cmd.ProcessState, err))
+		}
+		return nil
+	case <-time.After(timeout):
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		<-done
+		return fmt.Errorf("Health check timed out after %s", timeout)
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
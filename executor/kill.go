@@ -0,0 +1,94 @@
+package main
+
+import (
+	"log"
+	"syscall"
+	"time"
+
+	"github.com/vladimirvivien/mesos-http/mesos/mesos"
+)
+
+// defaultGracePeriod applies to tasks without a KillPolicy
+const defaultGracePeriod = 3 * time.Second
+
+// kill stops a running task: TASK_KILLING is reported if the
+// framework supports it, the task's process group gets SIGTERM and,
+// if still alive after the KillPolicy grace period, SIGKILL. The
+// task is then reported TASK_KILLED once its process is reaped.
+// A positive limit caps the grace period.
+func (e *executor) kill(taskID string, limit time.Duration) {
+	e.mu.Lock()
+	t, ok := e.tasks[taskID]
+	if ok && t.killing {
+		ok = false
+	}
+	if ok {
+		t.killing = true
+	}
+	e.mu.Unlock()
+	if !ok {
+		log.Println("Ignoring kill of unknown or already killed task ", taskID)
+		return
+	}
+
+	// the task may still be starting, or end meanwhile
+	<-t.started
+	if t.cmd == nil || e.exited(t) {
+		return
+	}
+
+	if e.hasCapability(mesos.FrameworkInfo_Capability_TASK_KILLING_STATE) {
+		if err := e.report(t, mesos.TaskState_TASK_KILLING, ""); err != nil {
+			log.Println("Failed while sending update:", err)
+		}
+	}
+
+	grace := defaultGracePeriod
+	if p := t.info.GetKillPolicy().GetGracePeriod(); p != nil {
+		grace = time.Duration(p.GetNanoseconds())
+	}
+	if limit > 0 && grace > limit {
+		grace = limit
+	}
+
+	log.Println("Sending SIGTERM to task ", taskID, ", grace period ", grace)
+	e.signalGroup(t, syscall.SIGTERM)
+	select {
+	case <-t.done:
+	case <-time.After(grace):
+		log.Println("Task ", taskID, " still running after grace period, sending SIGKILL")
+		e.signalGroup(t, syscall.SIGKILL)
+	}
+}
+
+// exited reports whether the process of a task was reaped
+func (e *executor) exited(t *task) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	select {
+	case <-t.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// signalGroup signals the process group of the task, unless its
+// process was reaped and the group ID may be reused.
+func (e *executor) signalGroup(t *task, sig syscall.Signal) {
+	if e.exited(t) {
+		return
+	}
+	if err := syscall.Kill(-t.cmd.Process.Pid, sig); err != nil && err != syscall.ESRCH {
+		log.Println("Unable to signal task ", t.info.GetTaskId().GetValue(), ": ", err)
+	}
+}
+
+func (e *executor) hasCapability(c mesos.FrameworkInfo_Capability_Type) bool {
+	for _, capability := range e.framework.GetCapabilities() {
+		if capability.GetType() == c {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	exec "github.com/vladimirvivien/mesos-http/mesos/exec"
+	"github.com/vladimirvivien/mesos-http/message"
+	"github.com/vladimirvivien/mesos-http/tasklog"
+)
+
+// maxTailLines bounds the lines returned by a tail request
+const maxTailLines = 1000
+
+// taskLogs are the rotated stdout and stderr files of a task
+type taskLogs struct {
+	stdout *tasklog.Writer
+	stderr *tasklog.Writer
+}
+
+func (l *taskLogs) Close() {
+	l.stdout.Close()
+	l.stderr.Close()
+}
+
+// configureLogs reads the sandbox directory, MESOS_SANDBOX or
+// MESOS_DIRECTORY. Task output is not captured without a sandbox.
+// Rotation is set by EXECUTOR_LOG_MAX_SIZE (bytes) and
+// EXECUTOR_LOG_MAX_FILES, read by configure.
+func (e *executor) configureLogs() {
+	e.sandbox = os.Getenv("MESOS_SANDBOX")
+	if e.sandbox == "" {
+		e.sandbox = os.Getenv("MESOS_DIRECTORY")
+	}
+}
+
+// logPath returns the file of a task stream, <sandbox>/<task id>/<stream>
+func (e *executor) logPath(taskID, stream string) string {
+	return filepath.Join(e.sandbox, taskID, stream)
+}
+
+// openLogs creates the log files of a task in the sandbox
+func (e *executor) openLogs(taskID string) (*taskLogs, error) {
+	if err := os.MkdirAll(filepath.Join(e.sandbox, taskID), 0755); err != nil {
+		return nil, err
+	}
+	e.mu.Lock()
+	maxSize, maxFiles := e.logMaxSize, e.logMaxFiles
+	e.mu.Unlock()
+
+	stdout, err := tasklog.NewWriter(e.logPath(taskID, tasklog.Stdout), maxSize, maxFiles)
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := tasklog.NewWriter(e.logPath(taskID, tasklog.Stderr), maxSize, maxFiles)
+	if err != nil {
+		stdout.Close()
+		return nil, err
+	}
+	return &taskLogs{stdout: stdout, stderr: stderr}, nil
+}
+
+// tail handles a tail request from the scheduler
+func (e *executor) tail(msg *message.Message) (interface{}, error) {
+	req := new(tasklog.TailRequest)
+	if err := msg.Decode(req); err != nil {
+		return nil, err
+	}
+	if req.Stream != tasklog.Stdout && req.Stream != tasklog.Stderr {
+		return nil, fmt.Errorf("Unknown stream %q", req.Stream)
+	}
+	resp := &tasklog.TailResponse{TaskID: req.TaskID, Stream: req.Stream}
+
+	lines := req.Lines
+	if lines <= 0 || lines > maxTailLines {
+		lines = maxTailLines
+	}
+	e.mu.Lock()
+	maxFiles := e.logMaxFiles
+	e.mu.Unlock()
+
+	var err error
+	switch {
+	case e.sandbox == "":
+		resp.Error = "Task output is not captured"
+	case req.TaskID == "" || filepath.Base(req.TaskID) != req.TaskID:
+		resp.Error = fmt.Sprintf("Invalid task ID %q", req.TaskID)
+	default:
+		resp.Lines, err = tasklog.Tail(e.logPath(req.TaskID, req.Stream), maxFiles, lines)
+		if err != nil {
+			resp.Error = err.Error()
+		}
+	}
+	return resp, nil
+}
+
+// sendMessage sends data to the scheduler
+func (e *executor) sendMessage(data []byte) error {
+	call := &exec.Call{
+		Type:        exec.Call_MESSAGE.Enum(),
+		FrameworkId: e.frameworkID,
+		ExecutorId:  e.id,
+		Message:     &exec.Call_Message{Data: data},
+	}
+	resp, err := e.send(call)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("Message returned unexpected response status: %d", resp.StatusCode)
+	}
+	return nil
+}
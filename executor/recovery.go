@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// configureRecovery reads MESOS_CHECKPOINT, set to 1 when the
+// framework checkpoints, in which case the executor survives agent
+// restarts. Timeouts are read by configure.
+func (e *executor) configureRecovery() {
+	e.checkpoint = os.Getenv("MESOS_CHECKPOINT") == "1"
+}
+
+// run streams events and, for a checkpointing framework,
+// resubscribes when the agent connection drops.
+func (e *executor) run(resp *http.Response) {
+	defer close(e.events)
+	for {
+		e.qEvents(resp)
+		if e.stopping() {
+			return
+		}
+		if !e.checkpoint {
+			log.Println("Disconnected from agent and framework does not checkpoint, exiting")
+			return
+		}
+
+		var err error
+		if resp, err = e.resubscribe(); err != nil {
+			log.Println(err)
+			return
+		}
+	}
+}
+
+// resubscribe retries the subscription with a randomized backoff,
+// doubling up to the backoff max, until the recovery timeout.
+func (e *executor) resubscribe() (*http.Response, error) {
+	e.mu.Lock()
+	timeout, backoffMax := e.recoveryTimeout, e.backoffMax
+	e.mu.Unlock()
+
+	log.Println("Disconnected from agent, resubscribing within ", timeout)
+	deadline := time.Now().Add(timeout)
+	backoff := backoffMax / 16
+	if backoff <= 0 {
+		backoff = time.Millisecond
+	}
+
+	for {
+		delay := time.Duration(rand.Int63n(int64(backoff) + 1))
+		if time.Now().Add(delay).After(deadline) {
+			return nil, fmt.Errorf("Unable to resubscribe within recovery timeout %s", timeout)
+		}
+		time.Sleep(delay)
+		if e.stopping() {
+			return nil, fmt.Errorf("Executor stopped while resubscribing")
+		}
+
+		resp, err := e.subscribe()
+		if err == nil {
+			log.Println("Resubscribed with agent")
+			return resp, nil
+		}
+		log.Println("Unable to resubscribe: ", err)
+
+		if backoff *= 2; backoff > backoffMax {
+			backoff = backoffMax
+		}
+	}
+}
+
+func (e *executor) stopping() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.shuttingDown
+}
+
+// durationUnits are the units of Mesos duration strings,
+// e.g. "15mins" or "2secs".
+var durationUnits = []struct {
+	suffix string
+	unit   time.Duration
+}{
+	{"ns", time.Nanosecond},
+	{"us", time.Microsecond},
+	{"ms", time.Millisecond},
+	{"secs", time.Second},
+	{"mins", time.Minute},
+	{"hrs", time.Hour},
+	{"days", 24 * time.Hour},
+	{"weeks", 7 * 24 * time.Hour},
+}
+
+// parseDuration parses a duration as formatted by Mesos, falling
+// back to Go duration syntax.
+func parseDuration(s string) (time.Duration, error) {
+	for _, u := range durationUnits {
+		if strings.HasSuffix(s, u.suffix) {
+			v, err := strconv.ParseFloat(strings.TrimSuffix(s, u.suffix), 64)
+			if err != nil {
+				break
+			}
+			return time.Duration(v * float64(u.unit)), nil
+		}
+	}
+	return time.ParseDuration(s)
+}
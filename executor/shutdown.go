@@ -0,0 +1,56 @@
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// shutdown kills all running tasks and stops the executor once their
+// terminal updates are acknowledged, or when the shutdown grace
+// period is over.
+// Part of the grace period is kept to reap the tasks and send their
+// final updates, so kill grace periods are capped accordingly.
+func (e *executor) shutdown() {
+	e.mu.Lock()
+	e.terminating = true
+	grace := e.shutdownGrace
+	taskIDs := make([]string, 0, len(e.tasks))
+	for id := range e.tasks {
+		taskIDs = append(taskIDs, id)
+	}
+	e.mu.Unlock()
+
+	log.Println("Killing ", len(taskIDs), " tasks within ", grace)
+	limit := grace * 4 / 5
+	for _, id := range taskIDs {
+		go e.kill(id, limit)
+	}
+
+	deadline := time.After(grace)
+	done := make(chan struct{})
+	go func() {
+		e.running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-deadline:
+		log.Println("Tasks still running after shutdown grace period")
+		e.stop()
+		return
+	}
+
+	// final updates may still wait for acknowledgements
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for !e.drained() {
+		select {
+		case <-ticker.C:
+		case <-deadline:
+			log.Println("Updates still unacknowledged after shutdown grace period")
+			e.stop()
+			return
+		}
+	}
+	e.stop()
+}
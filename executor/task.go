@@ -0,0 +1,283 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	osexec "os/exec"
+	"os/user"
+	"strconv"
+	"syscall"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/vladimirvivien/mesos-http/mesos/mesos"
+	"github.com/vladimirvivien/mesos-http/registry"
+)
+
+// task is a task run by the executor. Each task has its own
+// lifecycle: tasks are launched, killed and reaped independently.
+type task struct {
+	info    *mesos.TaskInfo
+	cmd     *osexec.Cmd
+	logs    *taskLogs
+	state   mesos.TaskState
+	started chan struct{} // closed once the process started or failed to
+	done    chan struct{} // closed once the process is reaped
+	killing bool
+	// unhealthy is set when the task is killed by its health check
+	unhealthy bool
+}
+
+// commandOf returns the command of a task. A task launched with a
+// custom executor cannot carry a CommandInfo itself, so the command
+// may also be passed as a serialized CommandInfo in TaskInfo.Data.
+func commandOf(info *mesos.TaskInfo) (*mesos.CommandInfo, error) {
+	if info.Command != nil {
+		return info.Command, nil
+	}
+	if len(info.Data) == 0 {
+		return nil, nil
+	}
+	cmd := new(mesos.CommandInfo)
+	if err := proto.Unmarshal(info.Data, cmd); err != nil {
+		return nil, fmt.Errorf("Unable to decode task command: %s", err)
+	}
+	return cmd, nil
+}
+
+// buildCmd prepares the process for a command: through the shell
+// when CommandInfo.shell is set (the default), otherwise by executing
+// value with arguments as argv.
+func (e *executor) buildCmd(info *mesos.CommandInfo) (*osexec.Cmd, error) {
+	var cmd *osexec.Cmd
+	if info.GetShell() {
+		cmd = osexec.Command("/bin/sh", "-c", info.GetValue())
+	} else {
+		if info.GetValue() == "" {
+			return nil, fmt.Errorf("Command has no executable")
+		}
+		cmd = osexec.Command(info.GetValue())
+		if len(info.GetArguments()) > 0 {
+			cmd.Args = info.GetArguments()
+		}
+	}
+
+	cmd.Env = os.Environ()
+	for _, v := range info.GetEnvironment().GetVariables() {
+		cmd.Env = append(cmd.Env, v.GetName()+"="+v.GetValue())
+	}
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	// own process group, so that kills reach all descendants
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	// CommandInfo.user takes precedence over FrameworkInfo.user
+	name := info.GetUser()
+	if name == "" {
+		name = e.framework.GetUser()
+	}
+	if err := runAs(cmd, name); err != nil {
+		return nil, err
+	}
+	return cmd, nil
+}
+
+// runAs sets the credentials of cmd to those of the named user,
+// unless the executor already runs as that user.
+func runAs(cmd *osexec.Cmd, name string) error {
+	if name == "" {
+		return nil
+	}
+	current, err := user.Current()
+	if err == nil && current.Username == name {
+		return nil
+	}
+	u, err := user.Lookup(name)
+	if err != nil {
+		return fmt.Errorf("Unable to find user %s: %s", name, err)
+	}
+	uid, err := strconv.ParseUint(u.Uid, 10, 32)
+	if err != nil {
+		return err
+	}
+	gid, err := strconv.ParseUint(u.Gid, 10, 32)
+	if err != nil {
+		return err
+	}
+	cmd.SysProcAttr.Credential = &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid)}
+	return nil
+}
+
+// register tracks a launched task ahead of its launch, so that a kill
+// received meanwhile finds it. It returns nil if the task is not to be
+// launched as the executor is shutting down, reporting it TASK_FAILED.
+func (e *executor) register(info *mesos.TaskInfo) (*task, error) {
+	id := info.GetTaskId().GetValue()
+	t := &task{
+		info:    info,
+		state:   mesos.TaskState_TASK_STAGING,
+		started: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	e.mu.Lock()
+	if _, ok := e.tasks[id]; ok {
+		e.mu.Unlock()
+		return nil, fmt.Errorf("Task %s is already running", id)
+	}
+	if e.terminating {
+		e.mu.Unlock()
+		return nil, e.sendUpdate(info, mesos.TaskState_TASK_FAILED.Enum(), "Executor is shutting down")
+	}
+	e.tasks[id] = t
+	e.unackedTasks[id] = info
+	e.running.Add(1)
+	e.mu.Unlock()
+	return t, nil
+}
+
+// launch fetches the URIs of a registered task command, starts the
+// command and reports TASK_RUNNING, with the fetch results as message.
+// The process is then awaited in the background and the task reported
+// TASK_FINISHED or TASK_FAILED based on its exit status. It is run
+// concurrently for every launched task.
+func (e *executor) launch(t *task) error {
+	info := t.info
+	id := info.GetTaskId().GetValue()
+	defer close(t.started)
+
+	cmdInfo, err := commandOf(info)
+	if err != nil {
+		e.finish(t)
+		return e.report(t, mesos.TaskState_TASK_FAILED, err.Error())
+	}
+	if cmdInfo == nil {
+		// nothing to run
+		e.finish(t)
+		if err := e.report(t, mesos.TaskState_TASK_RUNNING, ""); err != nil {
+			return err
+		}
+		return e.report(t, mesos.TaskState_TASK_FINISHED, "Task has no command")
+	}
+
+	fetched, err := e.fetch(cmdInfo.GetUris())
+	if err != nil {
+		e.finish(t)
+		return e.report(t, mesos.TaskState_TASK_FAILED, err.Error())
+	}
+
+	cmd, err := e.buildCmd(cmdInfo)
+	if err == nil && e.sandbox != "" {
+		if t.logs, err = e.openLogs(id); err == nil {
+			cmd.Stdout, cmd.Stderr = t.logs.stdout, t.logs.stderr
+		}
+	}
+	if err == nil {
+		err = cmd.Start()
+	}
+	if err != nil {
+		if t.logs != nil {
+			t.logs.Close()
+		}
+		e.finish(t)
+		return e.report(t, mesos.TaskState_TASK_FAILED, err.Error())
+	}
+
+	e.mu.Lock()
+	t.cmd = cmd
+	e.mu.Unlock()
+	log.Println("Started task ", id, " with pid ", cmd.Process.Pid)
+
+	// the waiter starts once RUNNING is reported, so that the
+	// terminal update always follows it
+	err = e.report(t, mesos.TaskState_TASK_RUNNING, fetched)
+	go e.wait(t)
+	if err != nil {
+		return err
+	}
+	if info.HealthCheck != nil {
+		go e.checkHealth(t)
+	}
+	go e.reportUsage(t)
+	return nil
+}
+
+// wait reaps the task process and reports its outcome:
+// TASK_KILLED if it was killed, otherwise TASK_FINISHED or
+// TASK_FAILED based on its exit status.
+func (e *executor) wait(t *task) {
+	err := t.cmd.Wait()
+	if t.logs != nil {
+		t.logs.Close()
+	}
+	e.finish(t)
+
+	e.mu.Lock()
+	killed, unhealthy := t.killing, t.unhealthy
+	e.mu.Unlock()
+
+	state := mesos.TaskState_TASK_FINISHED
+	if killed {
+		state = mesos.TaskState_TASK_KILLED
+	} else if err != nil {
+		state = mesos.TaskState_TASK_FAILED
+	}
+	message := exitMessage(t.cmd.ProcessState, err)
+	log.Println("Task ", t.info.GetTaskId().GetValue(), ": ", message)
+
+	// as with the Mesos command executor, a task killed by its health
+	// check is reported TASK_KILLED and unhealthy.
+	status := e.newStatus(t.info, state.Enum(), message)
+	if killed && unhealthy {
+		status.Healthy = proto.Bool(false)
+	}
+	if err := e.reportStatus(t, status); err != nil {
+		log.Println("Failed while sending update:", err)
+	}
+}
+
+// finish forgets a task whose process is gone, or never started
+func (e *executor) finish(t *task) {
+	e.mu.Lock()
+	delete(e.tasks, t.info.GetTaskId().GetValue())
+	close(t.done)
+	e.mu.Unlock()
+}
+
+// report records the new state of a task and sends it to the agent.
+// Terminal updates release the task for shutdown.
+func (e *executor) report(t *task, state mesos.TaskState, message string) error {
+	return e.reportStatus(t, e.newStatus(t.info, state.Enum(), message))
+}
+
+func (e *executor) reportStatus(t *task, status *mesos.TaskStatus) error {
+	e.mu.Lock()
+	// a terminal update is the last one of a task
+	if registry.IsTerminal(t.state) {
+		e.mu.Unlock()
+		log.Println("Dropping ", status.GetState(), " update of terminated task ", t.info.GetTaskId().GetValue())
+		return nil
+	}
+	t.state = status.GetState()
+	e.mu.Unlock()
+
+	if registry.IsTerminal(status.GetState()) {
+		defer e.running.Done()
+	}
+	return e.sendStatus(status)
+}
+
+// exitMessage describes how a process terminated
+func exitMessage(state *os.ProcessState, err error) string {
+	if state == nil {
+		return fmt.Sprintf("Command failed: %s", err)
+	}
+	ws, ok := state.Sys().(syscall.WaitStatus)
+	if !ok {
+		return fmt.Sprintf("Command exited: %s", state)
+	}
+	if ws.Signaled() {
+		return fmt.Sprintf("Command terminated with signal %s", ws.Signal())
+	}
+	return fmt.Sprintf("Command exited with status %d", ws.ExitStatus())
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	exec "github.com/vladimirvivien/mesos-http/mesos/exec"
+	"github.com/vladimirvivien/mesos-http/mesos/mesos"
+	"github.com/vladimirvivien/mesos-http/registry"
+	"github.com/vladimirvivien/mesos-http/uuid"
+)
+
+// updateRetryInterval is the default delay before an update that
+// could not be delivered is sent again, with its original UUID.
+const updateRetryInterval = 10 * time.Second
+
+// sendStatus sends a status update, or queues it behind the update
+// of the same task in flight: Mesos allows a single unacknowledged
+// update per task, later ones are sent in order as acks arrive.
+// Updates are kept until acknowledged so they survive an agent
+// restart.
+func (e *executor) sendStatus(status *mesos.TaskStatus) error {
+	e.mu.Lock()
+	update, queued := e.enqueue(status)
+	e.mu.Unlock()
+	return e.deliver(update, queued)
+}
+
+// sendRunning sends a TASK_RUNNING update of a task, such as a change
+// of health, unless the task is being killed or terminated meanwhile.
+// The state is checked and the update queued under one lock, so that
+// it cannot follow the TASK_KILLING or terminal update.
+func (e *executor) sendRunning(t *task, status *mesos.TaskStatus) error {
+	e.mu.Lock()
+	if t.killing || registry.IsTerminal(t.state) {
+		e.mu.Unlock()
+		return nil
+	}
+	update, queued := e.enqueue(status)
+	e.mu.Unlock()
+	return e.deliver(update, queued)
+}
+
+// enqueue appends an update to those of its task; it is called with
+// e.mu held and returns true if an update is already in flight.
+func (e *executor) enqueue(status *mesos.TaskStatus) (*exec.Call_Update, bool) {
+	taskID := status.GetTaskId().GetValue()
+	update := &exec.Call_Update{Status: status}
+	e.updates[taskID] = append(e.updates[taskID], update)
+	return update, len(e.updates[taskID]) > 1
+}
+
+func (e *executor) deliver(update *exec.Call_Update, queued bool) error {
+	if queued {
+		status := update.GetStatus()
+		log.Println("Queued ", status.GetState(), " update of task ", status.GetTaskId().GetValue(), " until the previous one is acknowledged")
+		return nil
+	}
+	return e.post(update)
+}
+
+// post sends an update. If it is not delivered, it is retried later
+// unless acknowledged meanwhile.
+func (e *executor) post(update *exec.Call_Update) error {
+	call := &exec.Call{
+		Type:        exec.Call_UPDATE.Enum(),
+		FrameworkId: e.frameworkID,
+		ExecutorId:  e.id,
+		Update:      update,
+	}
+
+	resp, err := e.send(call)
+	if err == nil && resp.StatusCode != http.StatusAccepted {
+		err = fmt.Errorf("Update return unexpected response status: %d", resp.StatusCode)
+	}
+	if err != nil {
+		status := update.GetStatus()
+		time.AfterFunc(e.updateRetry, func() {
+			if err := e.resend(status.GetTaskId().GetValue(), status.GetUuid()); err != nil {
+				log.Println("Failed while resending update:", err)
+			}
+		})
+	}
+	return err
+}
+
+// resend sends again the update in flight of a task, with its
+// original UUID so that the agent can drop duplicates. It does
+// nothing if that update was acknowledged.
+func (e *executor) resend(taskID string, id []byte) error {
+	e.mu.Lock()
+	queue := e.updates[taskID]
+	if len(queue) == 0 || !bytes.Equal(queue[0].GetStatus().GetUuid(), id) {
+		e.mu.Unlock()
+		return nil
+	}
+	update := queue[0]
+	e.mu.Unlock()
+
+	if u, err := uuid.FromBytes(id); err == nil {
+		log.Println("Resending update ", u, " of task ", taskID)
+	}
+	return e.post(update)
+}
+
+// acknowledged forgets an acknowledged update, and the task it
+// belongs to, then sends the next queued update of the task.
+func (e *executor) acknowledged(ack *exec.Event_Acknowledged) {
+	taskID := ack.GetTaskId().GetValue()
+
+	e.mu.Lock()
+	delete(e.unackedTasks, taskID)
+	queue := e.updates[taskID]
+	if len(queue) == 0 || !bytes.Equal(queue[0].GetStatus().GetUuid(), ack.GetUuid()) {
+		e.mu.Unlock()
+		log.Println("Ignoring acknowledgement of unknown update of task ", taskID)
+		return
+	}
+	queue = queue[1:]
+	if len(queue) == 0 {
+		delete(e.updates, taskID)
+	} else {
+		e.updates[taskID] = queue
+	}
+	e.mu.Unlock()
+
+	if len(queue) > 0 {
+		go func() {
+			if err := e.post(queue[0]); err != nil {
+				log.Println("Failed while sending update:", err)
+			}
+		}()
+	}
+}
+
+// unacked returns the updates not yet acknowledged, in order
+// for each task.
+func (e *executor) unacked() []*exec.Call_Update {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	taskIDs := make([]string, 0, len(e.updates))
+	for id := range e.updates {
+		taskIDs = append(taskIDs, id)
+	}
+	sort.Strings(taskIDs)
+
+	var updates []*exec.Call_Update
+	for _, id := range taskIDs {
+		updates = append(updates, e.updates[id]...)
+	}
+	return updates
+}
+
+// drained reports whether all updates were acknowledged
+func (e *executor) drained() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.updates) == 0
+}
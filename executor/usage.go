@@ -0,0 +1,43 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/vladimirvivien/mesos-http/message"
+	"github.com/vladimirvivien/mesos-http/usage"
+)
+
+// reportUsage samples the resource usage of a task's process group
+// at the usage interval and sends it to the scheduler until the
+// task terminates.
+func (e *executor) reportUsage(t *task) {
+	taskID := t.info.GetTaskId().GetValue()
+	for {
+		e.mu.Lock()
+		interval := e.usageInterval
+		e.mu.Unlock()
+		if interval <= 0 {
+			return
+		}
+
+		select {
+		case <-t.done:
+			return
+		case <-time.After(interval):
+		}
+
+		stats, err := usage.Sample(t.cmd.Process.Pid)
+		if err != nil {
+			// the task may have exited meanwhile
+			continue
+		}
+		report, err := usage.NewReport(t.info.GetTaskId(), stats)
+		if err == nil {
+			err = e.messages.SendWith(message.Proto, message.Address{}, usage.ReportType, report)
+		}
+		if err != nil {
+			log.Println("Unable to report usage of task ", taskID, ": ", err)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package fetch
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type entry struct {
+	name, link, body string
+}
+
+func tarOf(t *testing.T, entries []entry) *bytes.Buffer {
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: 0644, Typeflag: tar.TypeReg, Size: int64(len(e.body))}
+		if e.link != "" {
+			hdr.Typeflag, hdr.Linkname, hdr.Size = tar.TypeSymlink, e.link, 0
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(e.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
+
+func TestUntar(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []entry
+		ok      bool
+	}{
+		{"files", []entry{{name: "a/b", body: "x"}, {name: "c", body: "y"}}, true},
+		{"link within", []entry{{name: "a/b", body: "x"}, {name: "l", link: "a/b"}}, true},
+		{"dotdot", []entry{{name: "../evil", body: "x"}}, false},
+		{"absolute link", []entry{{name: "l", link: "/etc"}}, false},
+		{"link escapes", []entry{{name: "l", link: "../x"}}, false},
+		{"chained links", []entry{{name: "l", link: "."}, {name: "l/a", link: "../x"}, {name: "a/evil", body: "x"}}, false},
+		{"write through link", []entry{{name: "d", link: "."}, {name: "d/d/d/f", body: "x"}}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			parent, err := ioutil.TempDir("", "untar")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(parent)
+			sandbox := filepath.Join(parent, "sandbox")
+			if err := os.Mkdir(sandbox, 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Mkdir(filepath.Join(parent, "x"), 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			err = untar(tarOf(t, test.entries), sandbox)
+			if test.ok && err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !test.ok && err == nil {
+				t.Fatal("expected an error")
+			}
+			if _, err := os.Stat(filepath.Join(parent, "x", "evil")); err == nil {
+				t.Fatal("archive wrote outside the sandbox")
+			}
+		})
+	}
+}
+
+func TestUnzipThroughLink(t *testing.T) {
+	parent, err := ioutil.TempDir("", "unzip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(parent)
+	sandbox := filepath.Join(parent, "sandbox")
+	if err := os.Mkdir(sandbox, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("..", filepath.Join(sandbox, "up")); err != nil {
+		t.Fatal(err)
+	}
+
+	archive := filepath.Join(parent, "a.zip")
+	f, err := os.Create(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	w, err := zw.Create("up/x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("evil"))
+	zw.Close()
+	f.Close()
+
+	if err := unzip(archive, sandbox); err == nil {
+		t.Fatal("expected an error")
+	}
+	if _, err := os.Stat(filepath.Join(parent, "x")); err == nil {
+		t.Fatal("archive wrote outside the sandbox")
+	}
+}
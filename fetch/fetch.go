@@ -0,0 +1,205 @@
+package fetch
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/vladimirvivien/mesos-http/mesos/mesos"
+)
+
+// Fetcher downloads URIs. URIs flagged cache are kept in CacheDir,
+// when set, and copied from there on later fetches.
+type Fetcher struct {
+	CacheDir string
+	Client   *http.Client
+}
+
+// FetchAll fetches the URIs into dir and returns a summary of each
+// fetch, stopping at the first failure.
+func (f *Fetcher) FetchAll(uris []*mesos.CommandInfo_URI, dir string) ([]string, error) {
+	var results []string
+	for _, uri := range uris {
+		result, err := f.Fetch(uri, dir)
+		if err != nil {
+			return results, fmt.Errorf("Failed to fetch %s: %s", uri.GetValue(), err)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// Fetch downloads a http, https or file URI, or a local path, into
+// dir, then extracts it if it is an archive or sets its exec bits.
+// It returns a summary of what was done.
+func (f *Fetcher) Fetch(uri *mesos.CommandInfo_URI, dir string) (string, error) {
+	name, dest, err := destination(uri, dir)
+	if err != nil {
+		return "", err
+	}
+
+	cached := false
+	if uri.GetCache() && f.CacheDir != "" {
+		cached, err = f.fetchCached(uri.GetValue(), dest)
+	} else {
+		err = f.download(uri.GetValue(), dest)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	notes := []string{}
+	if cached {
+		notes = append(notes, "cached")
+	}
+	if uri.GetExtract() && isArchive(name) {
+		if err := extract(dest, dir); err != nil {
+			return "", err
+		}
+		notes = append(notes, "extracted")
+	} else if uri.GetExecutable() {
+		if err := os.Chmod(dest, 0755); err != nil {
+			return "", err
+		}
+		notes = append(notes, "executable")
+	}
+
+	result := fmt.Sprintf("Fetched %s to %s", uri.GetValue(), name)
+	if len(notes) > 0 {
+		result += " (" + strings.Join(notes, ", ") + ")"
+	}
+	return result, nil
+}
+
+// fetchCached copies a URI from the cache, downloading it there
+// first if needed. It reports whether the cache had it.
+func (f *Fetcher) fetchCached(uri, dest string) (bool, error) {
+	if err := os.MkdirAll(f.CacheDir, 0755); err != nil {
+		return false, err
+	}
+	sum := sha256.Sum256([]byte(uri))
+	entry := filepath.Join(f.CacheDir, hex.EncodeToString(sum[:]))
+
+	hit := true
+	if _, err := os.Stat(entry); os.IsNotExist(err) {
+		hit = false
+		if err := f.download(uri, entry); err != nil {
+			return false, err
+		}
+	}
+	return hit, copyFile(entry, dest)
+}
+
+// download writes a URI to dest through a temporary file, so that
+// concurrent fetches never see a partial file.
+func (f *Fetcher) download(uri, dest string) error {
+	src, err := f.open(uri)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	return writeFile(dest, src)
+}
+
+func (f *Fetcher) open(uri string) (io.ReadCloser, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "http", "https":
+		client := f.Client
+		if client == nil {
+			client = http.DefaultClient
+		}
+		resp, err := client.Get(uri)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("unexpected response status: %s", resp.Status)
+		}
+		return resp.Body, nil
+	case "file":
+		return os.Open(u.Path)
+	case "":
+		return os.Open(uri)
+	}
+	return nil, fmt.Errorf("unsupported scheme %s", u.Scheme)
+}
+
+func copyFile(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	return writeFile(dest, in)
+}
+
+func writeFile(dest string, r io.Reader) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(dest), ".fetch-")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), dest)
+}
+
+// destination returns the name and path in dir a URI is fetched to:
+// its output file if set, within dir, or else the base name of the URI.
+func destination(uri *mesos.CommandInfo_URI, dir string) (string, string, error) {
+	out := uri.GetOutputFile()
+	if out == "" {
+		name, err := baseName(uri.GetValue())
+		if err != nil {
+			return "", "", err
+		}
+		return name, filepath.Join(dir, name), nil
+	}
+	dest, err := within(dir, out)
+	if err == nil && dest != filepath.Clean(dir) {
+		_, err = resolve(dir, filepath.Dir(dest))
+	}
+	if err != nil || dest == filepath.Clean(dir) {
+		return "", "", fmt.Errorf("output file %s escapes the sandbox", out)
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return "", "", err
+	}
+	return out, dest, nil
+}
+
+// baseName returns the file name of a URI
+func baseName(uri string) (string, error) {
+	p := uri
+	if u, err := url.Parse(uri); err == nil && u.Scheme != "" {
+		p = u.Path
+	}
+	name := path.Base(p)
+	if name == "." || name == "/" || name == ".." {
+		return "", fmt.Errorf("no file name in URI %s", uri)
+	}
+	return name, nil
+}
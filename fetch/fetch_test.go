@@ -0,0 +1,61 @@
+package fetch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFetchOutputFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fetch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "src.txt")
+	if err := ioutil.WriteFile(src, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sandbox := filepath.Join(dir, "sandbox")
+	if err := os.Mkdir(sandbox, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		spec string
+		file string
+		ok   bool
+	}{
+		{src, "src.txt", true},
+		{src + ";output=conf/app.txt", "conf/app.txt", true},
+		{src + ";output=../out.txt", "", false},
+		{src + ";output=.", "", false},
+	}
+	for _, test := range tests {
+		uri, err := ParseURI(test.spec)
+		if err != nil {
+			t.Fatalf("%s: %s", test.spec, err)
+		}
+		_, err = new(Fetcher).Fetch(uri, sandbox)
+		if !test.ok {
+			if err == nil {
+				t.Errorf("%s: expected an error", test.spec)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s", test.spec, err)
+			continue
+		}
+		if data, err := ioutil.ReadFile(filepath.Join(sandbox, test.file)); err != nil || string(data) != "data" {
+			t.Errorf("%s: %s not fetched: %v", test.spec, test.file, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "out.txt")); err == nil {
+		t.Error("fetched outside the sandbox")
+	}
+	if _, err := ParseURI(src + ";output=/etc/passwd"); err == nil {
+		t.Error("expected an error for an absolute output file")
+	}
+}
@@ -0,0 +1,66 @@
+// Package fetch downloads the URIs of a CommandInfo into a sandbox,
+// extracting archives and setting exec bits, with a local cache.
+package fetch
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/vladimirvivien/mesos-http/mesos/mesos"
+)
+
+// ParseURI parses a URI spec <uri>[;option...] where the options
+// are executable, cache, noextract and output=<path>; archives are
+// extracted unless noextract is given. The output path names the
+// fetched file, relative to the sandbox.
+func ParseURI(spec string) (*mesos.CommandInfo_URI, error) {
+	parts := strings.Split(spec, ";")
+	value := strings.TrimSpace(parts[0])
+	if value == "" {
+		return nil, fmt.Errorf("fetch: empty URI in %q", spec)
+	}
+	uri := &mesos.CommandInfo_URI{Value: proto.String(value)}
+	for _, opt := range parts[1:] {
+		if out := strings.TrimPrefix(strings.TrimSpace(opt), "output="); out != strings.TrimSpace(opt) {
+			if out == "" || path.IsAbs(out) {
+				return nil, fmt.Errorf("fetch: invalid output file in %q", spec)
+			}
+			uri.OutputFile = proto.String(out)
+			continue
+		}
+		switch strings.TrimSpace(opt) {
+		case "executable":
+			uri.Executable = proto.Bool(true)
+		case "cache":
+			uri.Cache = proto.Bool(true)
+		case "noextract":
+			uri.Extract = proto.Bool(false)
+		default:
+			return nil, fmt.Errorf("fetch: unknown option %q in %q", opt, spec)
+		}
+	}
+	return uri, nil
+}
+
+// URIList is a flag.Value collecting URI specs given repeatedly
+type URIList []*mesos.CommandInfo_URI
+
+func (l *URIList) String() string {
+	values := make([]string, len(*l))
+	for i, uri := range *l {
+		values[i] = uri.GetValue()
+	}
+	return strings.Join(values, " ")
+}
+
+// Set parses and appends a URI spec
+func (l *URIList) Set(spec string) error {
+	uri, err := ParseURI(spec)
+	if err != nil {
+		return err
+	}
+	*l = append(*l, uri)
+	return nil
+}
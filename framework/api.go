@@ -0,0 +1,138 @@
+package framework
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/vladimirvivien/mesos-http/registry"
+)
+
+// TasksPath is the path of the task API served by Handler
+const TasksPath = "/tasks"
+
+// taskRequest is the body of a task submission, all fields optional
+type taskRequest struct {
+	Name     string            `json:"name"`
+	Priority int               `json:"priority"`
+	Labels   map[string]string `json:"labels"`
+	Volume   string            `json:"volume"`
+}
+
+// Handler returns the HTTP task API of the scheduler:
+//
+//	POST   /tasks                  submits a task described by a JSON
+//	                               taskRequest, answers the task ID
+//	DELETE /tasks/<id>             kills a task, or cancels it if pending
+//	DELETE /tasks?labels=<k=v,...> kills the tasks with the labels
+//
+// Kills take an optional grace period, e.g. grace=10s, overriding
+// the kill policy of the tasks.
+func (s *Scheduler) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(TasksPath, s.serveTasks)
+	mux.HandleFunc(TasksPath+"/", s.serveTask)
+	return mux
+}
+
+func (s *Scheduler) serveTasks(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		s.serveSubmit(w, r)
+	case http.MethodDelete:
+		s.serveKill(w, r, "")
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Scheduler) serveTask(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, TasksPath+"/")
+	if id == "" || strings.Contains(id, "/") {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	s.serveKill(w, r, id)
+}
+
+// serveKill kills the task id, or the tasks selected by labels
+func (s *Scheduler) serveKill(w http.ResponseWriter, r *http.Request, id string) {
+	var grace time.Duration
+	if g := r.URL.Query().Get("grace"); g != "" {
+		var err error
+		if grace, err = time.ParseDuration(g); err != nil || grace < 0 {
+			http.Error(w, fmt.Sprintf("Invalid grace period %q", g), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if id != "" {
+		if _, ok := s.queue.Get(id); !ok {
+			if _, ok := s.registry.Get(id); !ok {
+				http.NotFound(w, r)
+				return
+			}
+		}
+		if err := s.KillTask(id, grace); err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	// an empty selector would match every task
+	selector, err := registry.ParseLabels(r.URL.Query().Get("labels"))
+	if err == nil && len(selector) == 0 {
+		err = fmt.Errorf("missing labels")
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid labels: %s", err), http.StatusBadRequest)
+		return
+	}
+	// the tasks killed are reported along with the kills that failed
+	killed, err := s.killTasks(selector, grace)
+	status, body := http.StatusAccepted, map[string]interface{}{"killed": killed}
+	if err != nil {
+		status, body["error"] = http.StatusInternalServerError, err.Error()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func (s *Scheduler) serveSubmit(w http.ResponseWriter, r *http.Request) {
+	req := new(taskRequest)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid task: %s", err), http.StatusBadRequest)
+		return
+	}
+	if req.Volume != "" {
+		if s.Volumes == nil {
+			http.Error(w, "Persistent volumes require reserved disk", http.StatusBadRequest)
+			return
+		}
+		if _, ok := s.Volumes.Get(req.Volume); !ok {
+			http.Error(w, fmt.Sprintf("Unknown volume %s", req.Volume), http.StatusBadRequest)
+			return
+		}
+	}
+	if s.ShuttingDown() {
+		http.Error(w, "Scheduler is shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	id, err := s.Submit(req.Name, req.Priority, req.Labels, req.Volume)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]string{"id": id})
+}
@@ -0,0 +1,140 @@
+package framework
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/vladimirvivien/mesos-http/mesos/mesos"
+	"github.com/vladimirvivien/mesos-http/mesos/sched"
+	"github.com/vladimirvivien/mesos-http/mesostest"
+)
+
+func TestSubmitAPI(t *testing.T) {
+	s := New("127.0.0.1:0", &mesos.FrameworkInfo{User: proto.String("u"), Name: proto.String("f")})
+	api := httptest.NewServer(s.Handler())
+	defer api.Close()
+
+	resp, err := http.Post(api.URL+TasksPath, "application/json",
+		strings.NewReader(`{"name": "web", "priority": 2, "labels": {"tier": "front"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("submit returned %s", resp.Status)
+	}
+	var created struct{ ID string }
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+	task, ok := s.queue.Get(created.ID)
+	if !ok {
+		t.Fatalf("task %q not queued", created.ID)
+	}
+	if task.Name != "web" || task.Priority != 2 || task.Labels["tier"] != "front" {
+		t.Errorf("queued task %+v", task)
+	}
+
+	for _, test := range []struct {
+		method, body string
+		status       int
+	}{
+		{http.MethodPost, `{"name": `, http.StatusBadRequest},
+		{http.MethodPost, `{"volume": "db"}`, http.StatusBadRequest},
+		{http.MethodGet, ``, http.StatusMethodNotAllowed},
+	} {
+		req, _ := http.NewRequest(test.method, api.URL+TasksPath, strings.NewReader(test.body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != test.status {
+			t.Errorf("%s %s returned %s, want %d", test.method, test.body, resp.Status, test.status)
+		}
+	}
+	if n := s.queue.Len(); n != 1 {
+		t.Errorf("%d tasks queued, want 1", n)
+	}
+}
+
+func del(t *testing.T, url string) *http.Response {
+	req, err := http.NewRequest(http.MethodDelete, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+func TestKillAPI(t *testing.T) {
+	master := mesostest.NewMaster()
+	defer master.Close()
+	master.Script(mesostest.OffersEvent(mesostest.Offer("o1", "a1", "h1", 4, 1024)))
+
+	s := New(master.Addr(), &mesos.FrameworkInfo{User: proto.String("u"), Name: proto.String("f")})
+	api := httptest.NewServer(s.Handler())
+	defer api.Close()
+
+	web, _ := s.Submit("web", 0, map[string]string{"tier": "front"}, "")
+	db, _ := s.Submit("db", 0, map[string]string{"tier": "back"}, "")
+	done := s.Start()
+	if _, err := master.WaitForCall(sched.Call_ACCEPT, 1, 5*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	pending, _ := s.Submit("batch", 0, map[string]string{"tier": "back"}, "")
+
+	if resp := del(t, api.URL+TasksPath+"/"+web+"?grace=1s"); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("kill returned %s", resp.Status)
+	}
+	kills, err := master.WaitForCall(sched.Call_KILL, 1, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id := kills[0].GetKill().GetTaskId().GetValue(); id != web {
+		t.Errorf("killed %s, want %s", id, web)
+	}
+	if ns := kills[0].GetKill().GetKillPolicy().GetGracePeriod().GetNanoseconds(); ns != int64(time.Second) {
+		t.Errorf("kill grace period %dns, want 1s", ns)
+	}
+
+	// the pending task is cancelled, the launched one killed
+	if resp := del(t, api.URL+TasksPath+"?labels=tier=back"); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("kill by labels returned %s", resp.Status)
+	}
+	kills, err = master.WaitForCall(sched.Call_KILL, 2, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id := kills[1].GetKill().GetTaskId().GetValue(); id != db {
+		t.Errorf("killed %s, want %s", id, db)
+	}
+	if _, ok := s.queue.Get(pending); ok {
+		t.Errorf("pending task %s not cancelled", pending)
+	}
+
+	for _, test := range []struct {
+		url    string
+		status int
+	}{
+		{TasksPath + "/unknown", http.StatusNotFound},
+		{TasksPath + "/" + web + "?grace=soon", http.StatusBadRequest},
+		{TasksPath, http.StatusBadRequest},
+	} {
+		if resp := del(t, api.URL+test.url); resp.StatusCode != test.status {
+			t.Errorf("DELETE %s returned %s, want %d", test.url, resp.Status, test.status)
+		}
+	}
+
+	s.Shutdown(false, 0, 0)
+	<-done
+}
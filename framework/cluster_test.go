@@ -0,0 +1,91 @@
+package framework
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/vladimirvivien/mesos-http/mesos/mesos"
+	"github.com/vladimirvivien/mesos-http/mesostest/cluster"
+	"github.com/vladimirvivien/mesos-http/reservation"
+	"github.com/vladimirvivien/mesos-http/volume"
+)
+
+// TestReservedVolume runs a task on a persistent volume created on
+// reserved disk of a simulated cluster.
+func TestReservedVolume(t *testing.T) {
+	c := cluster.New(cluster.Config{
+		Agents:        []cluster.AgentSpec{{ID: "a1", Hostname: "h1", Cpus: 2, Mem: 256, Disk: 100}},
+		OfferInterval: 10 * time.Millisecond,
+		StartLatency:  10 * time.Millisecond,
+		RunTime:       50 * time.Millisecond,
+	})
+	defer c.Close()
+
+	fw := frameworkInfo()
+	fw.Role = proto.String("r")
+	s := New(c.Addr(), fw)
+	reservations, err := NewReservations("r", "p", "cpus:1,mem:128,disk:64", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Reservations = reservations
+	s.Volumes = volume.NewManager(reservations)
+	specs, err := ParseVolumes([]string{"v1:32:data"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CreateVolume(specs[0]); err != nil {
+		t.Fatal(err)
+	}
+	id, err := s.Submit("", 0, nil, "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the scheduler keeps handling offers once the task finished
+	s.KeepRunning = true
+	done := s.Start()
+	defer func() {
+		s.Shutdown(false, 0, 0)
+		// the event stream may outlive the teardown until the
+		// master closes its connections
+		c.Close()
+		<-done
+	}()
+
+	// an offer may show the resources of the task before its terminal
+	// update is handled, and have them unreserved until the next offer:
+	// the cluster ends up holding the reservations and the volume.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		state, _ := c.TaskState(id)
+		reserved, vol := reservedOn(c, reservations, "a1")
+		if state == mesos.TaskState_TASK_FINISHED && vol == 32 &&
+			reserved["cpus"] == 1 && reserved["mem"] == 128 && reserved["disk"] == 64 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("task %s, reserved %v with a volume of %v MB, want TASK_FINISHED, cpus:1, mem:128, disk:64 and 32 MB",
+				state, reserved, vol)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// reservedOn returns the resources of an agent reserved by m, volumes
+// included, and the size of the volume v1
+func reservedOn(c *cluster.Cluster, m *reservation.Manager, agentID string) (map[string]float64, float64) {
+	reserved := make(map[string]float64)
+	vol := 0.0
+	for _, res := range c.Resources(agentID) {
+		if !m.Owns(res) {
+			continue
+		}
+		if res.GetDisk().GetPersistence().GetId() == "v1" {
+			vol = res.GetScalar().GetValue()
+		}
+		reserved[res.GetName()] += res.GetScalar().GetValue()
+	}
+	return reserved, vol
+}
@@ -0,0 +1,118 @@
+package framework
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/vladimirvivien/mesos-http/mesos/mesos"
+	"github.com/vladimirvivien/mesos-http/mesos/sched"
+	"github.com/vladimirvivien/mesos-http/registry"
+)
+
+// KillTask asks the master to kill a task. A positive grace period
+// overrides the task's KillPolicy; otherwise the policy from the
+// TaskInfo applies. A task still waiting in the queue is cancelled.
+// The task moves through TASK_KILLING and TASK_KILLED as the
+// executor reports it.
+func (s *Scheduler) KillTask(taskID string, grace time.Duration) error {
+	if s.cancel(taskID) {
+		log.Println("Cancelled pending task ", taskID)
+		return nil
+	}
+
+	task, ok := s.registry.Get(taskID)
+	if !ok {
+		return fmt.Errorf("unknown task %s", taskID)
+	}
+	if err := s.registry.RequestKill(taskID); err != nil {
+		return err
+	}
+	s.persist()
+
+	call := &sched.Call{
+		FrameworkId: s.FrameworkID(),
+		Type:        sched.Call_KILL.Enum(),
+		Kill: &sched.Call_Kill{
+			TaskId:  task.Info.GetTaskId(),
+			AgentId: &mesos.AgentID{Value: proto.String(task.AgentID)},
+		},
+	}
+	if grace > 0 {
+		call.Kill.KillPolicy = &mesos.KillPolicy{
+			GracePeriod: &mesos.DurationInfo{
+				Nanoseconds: proto.Int64(grace.Nanoseconds()),
+			},
+		}
+	}
+
+	log.Println("Killing task ", taskID, " with grace period ", grace)
+	resp, err := s.Send(call)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("Kill call returned unexpected status: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// killTasks kills every active task whose labels match the selector
+// and returns the number of kill requests sent. A failed kill does
+// not stop the others; the failures are returned together.
+func (s *Scheduler) killTasks(selector map[string]string, grace time.Duration) (int, error) {
+	killed := 0
+	for _, pending := range s.queue.Tasks() {
+		if registry.MatchLabels(registry.ToLabels(pending.Labels), selector) && s.cancel(pending.ID) {
+			killed++
+		}
+	}
+	var failed []string
+	for _, task := range s.registry.WithLabels(selector) {
+		if err := s.KillTask(task.ID(), grace); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %s", task.ID(), err))
+			continue
+		}
+		killed++
+	}
+	if len(failed) > 0 {
+		return killed, fmt.Errorf("Unable to kill %d tasks: %s", len(failed), strings.Join(failed, "; "))
+	}
+	return killed, nil
+}
+
+// ShutdownExecutor asks the master to shut down an executor; the
+// executor is expected to kill its tasks before terminating.
+func (s *Scheduler) ShutdownExecutor(executorID, agentID string) error {
+	for _, task := range s.registry.OnAgent(agentID) {
+		if task.Terminal() || task.Info.GetExecutor().GetExecutorId().GetValue() != executorID {
+			continue
+		}
+		if err := s.registry.RequestKill(task.ID()); err != nil {
+			log.Println("Unable to mark task for kill: ", err)
+		}
+	}
+	s.persist()
+
+	call := &sched.Call{
+		FrameworkId: s.FrameworkID(),
+		Type:        sched.Call_SHUTDOWN.Enum(),
+		Shutdown: &sched.Call_Shutdown{
+			ExecutorId: &mesos.ExecutorID{Value: proto.String(executorID)},
+			AgentId:    &mesos.AgentID{Value: proto.String(agentID)},
+		},
+	}
+
+	log.Println("Shutting down executor ", executorID, " on agent ", agentID)
+	resp, err := s.Send(call)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("Shutdown call returned unexpected status: %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package framework
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/vladimirvivien/mesos-http/mesos/mesos"
+	"github.com/vladimirvivien/mesos-http/mesos/sched"
+	"github.com/vladimirvivien/mesos-http/mesostest"
+	"github.com/vladimirvivien/mesos-http/operation"
+	"github.com/vladimirvivien/mesos-http/queue"
+)
+
+func TestKillTask(t *testing.T) {
+	master := mesostest.NewMaster()
+	defer master.Close()
+	master.Script(mesostest.OffersEvent(mesostest.Offer("o1", "a1", "h1", 4, 1024)))
+
+	s := New(master.Addr(), frameworkInfo())
+	graceful, _ := s.Submit("", 0, nil, "")
+	plain, _ := s.Submit("", 0, nil, "")
+	done := s.Start()
+	waitFor(t, master, sched.Call_ACCEPT, 1)
+
+	if err := s.KillTask(graceful, 3*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.KillTask(plain, 0); err != nil {
+		t.Fatal(err)
+	}
+	kills := waitFor(t, master, sched.Call_KILL, 2)
+	kill := kills[0].GetKill()
+	if kill.GetTaskId().GetValue() != graceful || kill.GetAgentId().GetValue() != "a1" {
+		t.Errorf("killed %s on %s, want %s on a1", kill.GetTaskId().GetValue(), kill.GetAgentId().GetValue(), graceful)
+	}
+	if ns := kill.GetKillPolicy().GetGracePeriod().GetNanoseconds(); ns != int64(3*time.Second) {
+		t.Errorf("kill grace period %dns, want 3s", ns)
+	}
+	// without a grace period, the policy of the task applies
+	if kill := kills[1].GetKill(); kill.GetTaskId().GetValue() != plain || kill.KillPolicy != nil {
+		t.Errorf("kill of %s with policy %v, want %s without", kill.GetTaskId().GetValue(), kill.KillPolicy, plain)
+	}
+	if task, _ := s.registry.Get(graceful); task.KillRequested.IsZero() {
+		t.Error("kill not recorded")
+	}
+
+	// the kill is expected, the scheduler keeps running
+	master.Send(mesostest.UpdateEvent(mesostest.Status(graceful, "a1", mesos.TaskState_TASK_KILLING)))
+	master.Send(mesostest.UpdateEvent(mesostest.Status(graceful, "a1", mesos.TaskState_TASK_KILLED)))
+	waitFor(t, master, sched.Call_ACKNOWLEDGE, 2)
+
+	if err := s.KillTask("unknown", 0); err == nil {
+		t.Error("unknown task killed")
+	}
+	s.Shutdown(false, 0, 0)
+	<-done
+}
+
+func TestShutdownExecutor(t *testing.T) {
+	master := mesostest.NewMaster()
+	defer master.Close()
+	master.Script(mesostest.OffersEvent(mesostest.Offer("o1", "a1", "h1", 4, 1024)))
+
+	s := New(master.Addr(), frameworkInfo())
+	s.Prepare = func(task *mesos.TaskInfo, pending *queue.Task, plan *operation.Plan) error {
+		task.Executor = &mesos.ExecutorInfo{
+			ExecutorId: &mesos.ExecutorID{Value: proto.String("e1")},
+			Command:    &mesos.CommandInfo{Value: proto.String("executor")},
+		}
+		return nil
+	}
+	id, _ := s.Submit("", 0, nil, "")
+	done := s.Start()
+	waitFor(t, master, sched.Call_ACCEPT, 1)
+
+	if err := s.ShutdownExecutor("e1", "a1"); err != nil {
+		t.Fatal(err)
+	}
+	shutdown := waitFor(t, master, sched.Call_SHUTDOWN, 1)[0].GetShutdown()
+	if shutdown.GetExecutorId().GetValue() != "e1" || shutdown.GetAgentId().GetValue() != "a1" {
+		t.Errorf("shut down %v, want e1 on a1", shutdown)
+	}
+	// the tasks of the executor are expected to be killed
+	if task, _ := s.registry.Get(id); task.KillRequested.IsZero() {
+		t.Errorf("kill of %s not recorded", id)
+	}
+
+	s.Shutdown(false, 0, 0)
+	<-done
+}
+
+func TestKillTasksFailures(t *testing.T) {
+	master := mesostest.NewMaster()
+	defer master.Close()
+	master.Script(mesostest.OffersEvent(mesostest.Offer("o1", "a1", "h1", 4, 1024)))
+
+	s := New(master.Addr(), frameworkInfo())
+	selector := map[string]string{"tier": "back"}
+	for i := 0; i < 3; i++ {
+		s.Submit("", 0, selector, "")
+	}
+	done := s.Start()
+	waitFor(t, master, sched.Call_ACCEPT, 1)
+
+	// every task is killed even though the kills fail
+	master.Reject(sched.Call_KILL, http.StatusServiceUnavailable)
+	killed, err := s.killTasks(selector, 0)
+	if err == nil || killed != 0 {
+		t.Fatalf("failed kills returned %d, %v", killed, err)
+	}
+	if n := len(master.CallsOf(sched.Call_KILL)); n != 3 {
+		t.Fatalf("%d kills sent, want 3", n)
+	}
+
+	master.Reject(sched.Call_KILL, 0)
+	if killed, err := s.killTasks(selector, 0); err != nil || killed != 3 {
+		t.Fatalf("kills returned %d, %v", killed, err)
+	}
+	s.Shutdown(false, 0, 0)
+	<-done
+}
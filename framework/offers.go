@@ -0,0 +1,199 @@
+package framework
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/vladimirvivien/mesos-http/constraint"
+	"github.com/vladimirvivien/mesos-http/eventlog"
+	"github.com/vladimirvivien/mesos-http/mesos/mesos"
+	"github.com/vladimirvivien/mesos-http/operation"
+	"github.com/vladimirvivien/mesos-http/queue"
+	"github.com/vladimirvivien/mesos-http/registry"
+	"github.com/vladimirvivien/mesos-http/reservation"
+)
+
+// Offers handle incoming offers
+func (s *Scheduler) offers(offers []*mesos.Offer) {
+	for _, offer := range offers {
+		log.Println("Processing offer ", offer.Id.GetValue())
+
+		plan := operation.New(offer)
+		reserved, unreserved := s.reserve(plan)
+		var claimed []*queue.Task
+		s.planVolumes(plan, reservation.NewBudget(s.Reservations, plan.Remaining()))
+
+		for _, pending := range s.queue.Tasks() {
+			// launch nothing while shutting down, declining the offer
+			if s.ShuttingDown() {
+				break
+			}
+			budget := reservation.NewBudget(s.Reservations, plan.Remaining())
+			if budget.Available("cpus") < pending.Cpus || budget.Available("mem") < pending.Mem ||
+				!constraint.Satisfied(pending.Constraints, offer, s.placed()) {
+				continue
+			}
+			vol, ok := s.volumeFor(pending, plan)
+			if !ok {
+				continue
+			}
+
+			//log.Println("Preparing task with id ", pending.ID, " for launch")
+			task := &mesos.TaskInfo{
+				Name: proto.String(pending.Name),
+				TaskId: &mesos.TaskID{
+					Value: proto.String(pending.ID),
+				},
+				AgentId:   offer.AgentId,
+				Labels:    registry.ToLabels(pending.Labels),
+				Resources: append(budget.Take("cpus", pending.Cpus), budget.Take("mem", pending.Mem)...),
+			}
+			if vol != nil {
+				task.Resources = append(task.Resources, vol)
+			}
+			if s.Prepare != nil {
+				if err := s.Prepare(task, pending, plan); err != nil {
+					log.Println("Unable to prepare task ", pending.ID, ": ", err)
+					continue
+				}
+			}
+			if !s.claim(pending, task, plan) {
+				continue
+			}
+			s.place(pending.ID, offer)
+			claimed = append(claimed, pending)
+			atomic.AddUint64(&s.taskLaunched, 1)
+		}
+
+		// setup accept call
+		call := plan.Accept(s.FrameworkID())
+
+		s.persist()
+
+		// send call
+		resp, err := s.Send(call)
+		if err == nil && resp.StatusCode != http.StatusAccepted {
+			err = fmt.Errorf("unexpected status: %d", resp.StatusCode)
+		}
+		if err != nil {
+			log.Println("Unable to send Accept Call: ", err)
+			s.unclaim(claimed)
+			if s.Reservations != nil {
+				s.Reservations.Rollback(offer.GetAgentId().GetValue(), reserved, unreserved)
+			}
+			s.persist()
+		}
+	}
+}
+
+// claim takes a pending task off the queue to launch it on plan. The
+// task is registered ahead of its launch, and put back in the queue if
+// either fails, so that a task is never lost nor launched untracked.
+func (s *Scheduler) claim(pending *queue.Task, task *mesos.TaskInfo, plan *operation.Plan) bool {
+	// another offer may have claimed the task meanwhile
+	if !s.queue.Remove(pending.ID) {
+		return false
+	}
+	if err := s.registry.Add(task); err != nil {
+		log.Println("Unable to register task: ", err)
+		s.requeue(pending)
+		return false
+	}
+	if err := plan.Launch(task); err != nil {
+		log.Println("Unable to launch task ", pending.ID, ": ", err)
+		s.registry.Remove(pending.ID)
+		s.requeue(pending)
+		return false
+	}
+	return true
+}
+
+// unclaim puts back in the queue the tasks claimed for an offer that
+// could not be accepted, to launch them on a later offer.
+func (s *Scheduler) unclaim(claimed []*queue.Task) {
+	for _, pending := range claimed {
+		s.registry.Remove(pending.ID)
+		s.unplace(pending.ID)
+		s.requeue(pending)
+		atomic.AddUint64(&s.taskLaunched, ^uint64(0))
+	}
+	if len(claimed) > 0 {
+		log.Println("Requeued ", len(claimed), " tasks of the failed launch")
+	}
+}
+
+// requeue puts back a task that could not be launched
+func (s *Scheduler) requeue(pending *queue.Task) {
+	if err := s.queue.Push(pending); err != nil {
+		log.Println("Unable to requeue task ", pending.ID, ": ", err)
+	}
+}
+
+// Submit queues a new task for launch. It can be called while the
+// scheduler is running; the task is launched on a subsequent offer.
+func (s *Scheduler) Submit(name string, priority int, labels map[string]string, volume string) (string, error) {
+	seq := atomic.AddUint64(&s.taskSeq, 1)
+	taskID := fmt.Sprintf("%d-%d", s.epoch, seq)
+	if name == "" {
+		name = fmt.Sprintf("task-%s", taskID)
+	}
+	sub := &submission{ID: taskID, Name: name, Priority: priority, Labels: labels, Volume: volume}
+	if err := s.push(sub); err != nil {
+		return "", err
+	}
+	s.recordSubmit(sub)
+	s.persist()
+	return taskID, nil
+}
+
+// push queues the task of a submission
+func (s *Scheduler) push(sub *submission) error {
+	return s.queue.Push(&queue.Task{
+		ID:          sub.ID,
+		Name:        sub.Name,
+		Priority:    sub.Priority,
+		Cpus:        s.cpuPerTask,
+		Mem:         s.memPerTask,
+		Constraints: s.Constraints,
+		Labels:      sub.Labels,
+		URIs:        s.URIs,
+		Container:   s.Container,
+		Volume:      sub.Volume,
+	})
+}
+
+// cancel removes a task from the queue before it is launched
+func (s *Scheduler) cancel(taskID string) bool {
+	if !s.queue.Remove(taskID) {
+		return false
+	}
+	s.record(eventlog.Cancel, []byte(taskID))
+	s.persist()
+	return true
+}
+
+// placed returns the placements of tasks that have not terminated
+func (s *Scheduler) placed() []constraint.Placement {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	placed := make([]constraint.Placement, 0, len(s.placements))
+	for _, p := range s.placements {
+		placed = append(placed, p)
+	}
+	return placed
+}
+
+func (s *Scheduler) place(taskID string, offer *mesos.Offer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.placements[taskID] = constraint.PlacementOf(offer)
+}
+
+func (s *Scheduler) unplace(taskID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.placements, taskID)
+}
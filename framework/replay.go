@@ -0,0 +1,180 @@
+package framework
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/vladimirvivien/mesos-http/eventlog"
+	"github.com/vladimirvivien/mesos-http/mesos/sched"
+)
+
+// session holds the settings of a recorded session a replay needs
+// to make the same calls: the task ID epoch.
+type session struct {
+	Epoch int64 `json:"epoch"`
+}
+
+// submission is a submitted task as recorded, so that a replay queues
+// the tasks submitted while the session ran, e.g. through the task API.
+type submission struct {
+	ID       string            `json:"id"`
+	Name     string            `json:"name"`
+	Priority int               `json:"priority"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Volume   string            `json:"volume,omitempty"`
+}
+
+// record appends an event or call to the session log, if recording
+func (s *Scheduler) record(kind eventlog.Kind, data []byte) {
+	if s.Recorder == nil {
+		return
+	}
+	if err := s.Recorder.Write(kind, data); err != nil {
+		log.Println("Unable to record ", kind, ": ", err)
+	}
+}
+
+// recordSession records the session settings ahead of the events
+func (s *Scheduler) recordSession() {
+	if s.Recorder == nil {
+		return
+	}
+	data, err := json.Marshal(&session{Epoch: s.epoch})
+	if err != nil {
+		log.Println("Unable to encode session: ", err)
+		return
+	}
+	s.record(eventlog.Session, data)
+}
+
+// recordSubmit records a submitted task
+func (s *Scheduler) recordSubmit(sub *submission) {
+	if s.Recorder == nil {
+		return
+	}
+	data, err := json.Marshal(sub)
+	if err != nil {
+		log.Println("Unable to encode submission: ", err)
+		return
+	}
+	s.record(eventlog.Submit, data)
+}
+
+// resubmit queues a recorded submission with its recorded task ID.
+// Tasks already known, such as those the binaries submit on start,
+// are not queued again.
+func (s *Scheduler) resubmit(sub *submission) {
+	if _, ok := s.queue.Get(sub.ID); ok {
+		return
+	}
+	if _, ok := s.registry.Get(sub.ID); ok {
+		return
+	}
+	if err := s.push(sub); err != nil {
+		log.Println("Unable to queue recorded task ", sub.ID, ": ", err)
+		return
+	}
+	s.persist()
+}
+
+// dispatch runs an event handler. Handlers run concurrently, except
+// during a replay where they run in event order to be deterministic.
+func (s *Scheduler) dispatch(handler func()) {
+	if s.replaying {
+		handler()
+		return
+	}
+	go handler()
+}
+
+// LoadReplay reads a recorded session, replayed by Start instead of
+// subscribing to the master. The task ID epoch is restored from the
+// session so that replayed tasks get the IDs the recorded events
+// refer to.
+func (s *Scheduler) LoadReplay(path string) error {
+	r, err := eventlog.Open(path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	for {
+		entry, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if entry.Kind == eventlog.Session {
+			sess := new(session)
+			if err := json.Unmarshal(entry.Data, sess); err != nil {
+				return err
+			}
+			s.epoch = sess.Epoch
+			continue
+		}
+		s.replay = append(s.replay, entry)
+	}
+	s.replaying = true
+	s.handled = make(chan struct{})
+	return nil
+}
+
+// replayEvents feeds the recorded events to the event handlers, and
+// submits and cancels the recorded tasks in between. Recorded calls are
+// logged so they can be compared with the calls the scheduler makes
+// during the replay.
+func (s *Scheduler) replayEvents() {
+	defer close(s.events)
+	log.Println("Replaying ", len(s.replay), " recorded entries")
+	for _, entry := range s.replay {
+		switch entry.Kind {
+		case eventlog.Call:
+			call := new(sched.Call)
+			if err := proto.Unmarshal(entry.Data, call); err == nil {
+				log.Println("Recorded call at ", entry.Time, ": ", call.GetType())
+			}
+			continue
+		case eventlog.Submit:
+			sub := new(submission)
+			if err := json.Unmarshal(entry.Data, sub); err != nil {
+				log.Println("Unable to decode recorded submission: ", err)
+				continue
+			}
+			s.resubmit(sub)
+			continue
+		case eventlog.Cancel:
+			s.cancel(string(entry.Data))
+			continue
+		}
+
+		event := new(sched.Event)
+		if err := json.Unmarshal(entry.Data, event); err != nil {
+			log.Println("Unable to decode recorded event: ", err)
+			continue
+		}
+		// entries after the event apply once it is handled
+		s.events <- event
+		<-s.handled
+	}
+}
+
+// replayed returns the response the master would have sent
+func replayed(call *sched.Call) *http.Response {
+	log.Println("Replay: not sending call ", call.GetType())
+	status := http.StatusAccepted
+	if call.GetType() == sched.Call_SUBSCRIBE {
+		status = http.StatusOK
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     make(http.Header),
+		Body:       ioutil.NopCloser(bytes.NewReader(nil)),
+	}
+}
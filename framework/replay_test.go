@@ -0,0 +1,86 @@
+package framework
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/vladimirvivien/mesos-http/eventlog"
+	"github.com/vladimirvivien/mesos-http/mesos/sched"
+	"github.com/vladimirvivien/mesos-http/mesostest"
+)
+
+func TestReplaySubmits(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.log")
+	w, err := eventlog.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	master := mesostest.NewMaster()
+	defer master.Close()
+
+	s := New(master.Addr(), frameworkInfo())
+	s.Recorder = w
+	// submitted on start, as the binaries do
+	first, _ := s.Submit("", 0, nil, "")
+	done := s.Start()
+	// submitted while running, as through the task API
+	second, _ := s.Submit("", 1, map[string]string{"tier": "back"}, "")
+	cancelled, _ := s.Submit("", 0, nil, "")
+	if !s.cancel(cancelled) {
+		t.Fatalf("task %s not cancelled", cancelled)
+	}
+	if err := master.Send(mesostest.OffersEvent(mesostest.Offer("o1", "a1", "h1", 4, 1024))); err != nil {
+		t.Fatal(err)
+	}
+	want := launched(waitFor(t, master, sched.Call_ACCEPT, 1)[0])
+	if len(want) != 2 {
+		t.Fatalf("launched %v, want %s and %s", want, first, second)
+	}
+	s.Shutdown(false, 0, 0)
+	<-done
+	w.Close()
+
+	var calls bytes.Buffer
+	r := New(master.Addr(), frameworkInfo())
+	r.Recorder = eventlog.NewWriter(&calls)
+	if err := r.LoadReplay(path); err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := r.Submit("", 0, nil, ""); id != first {
+		t.Fatalf("replay submitted %s on start, want %s", id, first)
+	}
+	if !closed(r.Start(), 5*time.Second) {
+		t.Fatal("replay did not end")
+	}
+
+	// the replay launches the same tasks on the recorded offer
+	var got []string
+	reader := eventlog.NewReader(&calls)
+	for {
+		entry, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		call := new(sched.Call)
+		if entry.Kind != eventlog.Call || proto.Unmarshal(entry.Data, call) != nil {
+			continue
+		}
+		if call.GetType() == sched.Call_ACCEPT {
+			got = append(got, launched(call)...)
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("replay launched %v, want %v", got, want)
+	}
+	if _, ok := r.queue.Get(cancelled); ok {
+		t.Errorf("cancelled task %s queued by the replay", cancelled)
+	}
+}
@@ -0,0 +1,71 @@
+package framework
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/vladimirvivien/mesos-http/mesos/mesos"
+	"github.com/vladimirvivien/mesos-http/operation"
+	"github.com/vladimirvivien/mesos-http/registry"
+	"github.com/vladimirvivien/mesos-http/reservation"
+)
+
+// reserve plans the reservations of an offer ahead of the tasks
+// launched on it. It returns the resources planned to be reserved and
+// unreserved, rolled back if the offer is not accepted.
+func (s *Scheduler) reserve(plan *operation.Plan) (reserve, unreserve []*mesos.Resource) {
+	if s.Reservations == nil || s.ShuttingDown() {
+		return nil, nil
+	}
+	agentID := plan.Offer().GetAgentId().GetValue()
+	reserve, unreserve = s.Reservations.Plan(plan.Offer(), s.used(agentID))
+	if len(unreserve) > 0 {
+		log.Println("Unreserving ", unreserve, " on agent ", agentID)
+		if err := plan.Unreserve(unreserve...); err != nil {
+			log.Println("Unable to unreserve resources: ", err)
+			s.Reservations.Rollback(agentID, nil, unreserve)
+			unreserve = nil
+		}
+	}
+	if len(reserve) > 0 {
+		log.Println("Reserving ", reserve, " on agent ", agentID)
+		if err := plan.Reserve(reserve...); err != nil {
+			log.Println("Unable to reserve resources: ", err)
+			s.Reservations.Rollback(agentID, reserve, nil)
+			reserve = nil
+		}
+	}
+	return reserve, unreserve
+}
+
+// used returns the resources of the active tasks on an agent
+func (s *Scheduler) used(agentID string) []*mesos.Resource {
+	var used []*mesos.Resource
+	for _, task := range s.registry.OnAgent(agentID) {
+		if !task.Terminal() {
+			used = append(used, task.Info.GetResources()...)
+		}
+	}
+	return used
+}
+
+// NewReservations returns the manager of the reservations of role
+// up to target, a list of <name:amount,...>, labelled <key=value,...>.
+// It returns nil without target.
+func NewReservations(role, principal, target, labels string) (*reservation.Manager, error) {
+	if target == "" {
+		return nil, nil
+	}
+	if role == "" || role == "*" {
+		return nil, fmt.Errorf("-reserve requires a framework -role")
+	}
+	t, err := reservation.ParseTarget(target)
+	if err != nil {
+		return nil, err
+	}
+	l, err := registry.ParseLabels(labels)
+	if err != nil {
+		return nil, err
+	}
+	return reservation.NewManager(role, principal, l, t), nil
+}
@@ -0,0 +1,236 @@
+package framework
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"os/user"
+	"syscall"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/vladimirvivien/mesos-http/constraint"
+	"github.com/vladimirvivien/mesos-http/container"
+	"github.com/vladimirvivien/mesos-http/election"
+	"github.com/vladimirvivien/mesos-http/eventlog"
+	"github.com/vladimirvivien/mesos-http/mesos/mesos"
+	"github.com/vladimirvivien/mesos-http/registry"
+	"github.com/vladimirvivien/mesos-http/store"
+	"github.com/vladimirvivien/mesos-http/volume"
+)
+
+// Options are the command line options shared by the scheduler
+// binaries
+type Options struct {
+	Master            string
+	User              string
+	MaxTasks          int
+	StateDir          string
+	Failover          time.Duration
+	LeaseFile         string
+	LeaseTTL          time.Duration
+	Labels            string
+	KillOnExit        bool
+	KillGrace         time.Duration
+	ExitTimeout       time.Duration
+	API               string
+	Record            string
+	Replay            string
+	Constraints       string
+	Role              string
+	Principal         string
+	Reserve           string
+	ReservationLabels string
+	PersistentVolumes container.StringList
+	DestroyVolumes    container.StringList
+
+	// Handler serves the task API, the scheduler's Handler if nil
+	Handler http.Handler
+}
+
+// Flags defines the shared options on the command line, to be
+// parsed by the binary
+func Flags() *Options {
+	o := new(Options)
+	flag.StringVar(&o.Master, "master", "127.0.0.1:5050", "Master address <ip:port>")
+	flag.StringVar(&o.User, "user", "", "Framework user")
+	flag.IntVar(&o.MaxTasks, "maxtasks", 5, "Number of tasks queued at startup")
+	flag.StringVar(&o.StateDir, "state", "", "Directory to persist framework state, disabled if empty")
+	flag.DurationVar(&o.Failover, "failover", 0, "Framework failover timeout")
+	flag.StringVar(&o.LeaseFile, "lease", "", "Lease file for leader election among instances, disabled if empty")
+	flag.DurationVar(&o.LeaseTTL, "lease-ttl", 15*time.Second, "Leader lease duration")
+	flag.StringVar(&o.Labels, "labels", "", "Labels of the tasks queued at startup <key=value,...>")
+	flag.BoolVar(&o.KillOnExit, "kill-on-exit", false, "Kill running tasks when the scheduler is stopped")
+	flag.DurationVar(&o.KillGrace, "kill-grace", 0, "Grace period for tasks killed on exit, task policy if zero")
+	flag.DurationVar(&o.ExitTimeout, "exit-timeout", 30*time.Second, "Time to wait for killed tasks to terminate")
+	flag.StringVar(&o.API, "api", "", "Address to serve the task API on <ip:port>, disabled if empty")
+	flag.StringVar(&o.Record, "record", "", "File to record received events and sent calls to")
+	flag.StringVar(&o.Replay, "replay", "", "Replay a recorded session instead of subscribing to the master")
+	flag.StringVar(&o.Constraints, "constraints", "", "Placement constraints <field:OPERATOR[:value],...>")
+	flag.StringVar(&o.Role, "role", "", "Framework role")
+	flag.StringVar(&o.Principal, "principal", "", "Framework principal, reservations are made on its behalf")
+	flag.StringVar(&o.Reserve, "reserve", "", "Resources kept reserved for -role across agents <name:amount,...>, others are unreserved")
+	flag.StringVar(&o.ReservationLabels, "reservation-labels", "", "Labels of the reservations made with -reserve <key=value,...>")
+	flag.Var(&o.PersistentVolumes, "persistent-volume", "Persistent volume on the disk reserved with -reserve <id:size_mb:container_path>, repeatable; the i-th task queued at startup runs with the i-th volume")
+	flag.Var(&o.DestroyVolumes, "destroy-volume", "Persistence ID of a volume to destroy, repeatable")
+	return o
+}
+
+// FrameworkInfo returns the info of the framework name, run by the
+// current user unless -user is set
+func (o *Options) FrameworkInfo(name string) (*mesos.FrameworkInfo, error) {
+	if o.User == "" {
+		u, err := user.Current()
+		if err != nil {
+			return nil, errors.New("unable to determine user")
+		}
+		o.User = u.Username
+	}
+	fw := &mesos.FrameworkInfo{
+		User:     proto.String(o.User),
+		Name:     proto.String(name),
+		Hostname: proto.String(hostname()),
+	}
+	if o.Failover > 0 {
+		fw.FailoverTimeout = proto.Float64(o.Failover.Seconds())
+	}
+	if o.Role != "" {
+		fw.Role = proto.String(o.Role)
+	}
+	if o.Principal != "" {
+		fw.Principal = proto.String(o.Principal)
+	}
+	return fw, nil
+}
+
+func hostname() string {
+	name, err := os.Hostname()
+	if err != nil {
+		return "UNKNOWN"
+	}
+	return name
+}
+
+// Run configures s with the options, waits for leadership, restores
+// or queues the startup tasks and runs s until it is done or stopped
+// by SIGINT or SIGTERM. Errors in the options are fatal.
+func Run(s *Scheduler, o *Options) {
+	var err error
+	if s.Constraints, err = constraint.ParseList(o.Constraints); err != nil {
+		log.Fatal(err)
+	}
+	s.Reservations, err = NewReservations(o.Role, o.Principal, o.Reserve, o.ReservationLabels)
+	if err != nil {
+		log.Fatal(err)
+	}
+	persistent, err := ParseVolumes(o.PersistentVolumes)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if s.Reservations != nil {
+		s.Volumes = volume.NewManager(s.Reservations)
+	} else if len(persistent) > 0 || len(o.DestroyVolumes) > 0 {
+		log.Fatal("Persistent volumes require reserved disk, see -reserve")
+	}
+	labels, err := registry.ParseLabels(o.Labels)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if o.Record != "" {
+		w, err := eventlog.Create(o.Record)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer w.Close()
+		s.Recorder = w
+	}
+	// a replay runs without master, state store or election
+	if o.Replay != "" {
+		if err := s.LoadReplay(o.Replay); err != nil {
+			log.Fatal(err)
+		}
+		o.StateDir, o.LeaseFile, o.API = "", "", ""
+	}
+	if o.StateDir != "" {
+		fs, err := store.NewFileStore(o.StateDir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		s.Store = fs
+	}
+	// standbys block here; state is restored only once elected
+	// so that the leader resumes with the persisted framework ID.
+	var elector *election.FileLease
+	if o.LeaseFile != "" {
+		id := fmt.Sprintf("%s-%d", hostname(), os.Getpid())
+		if elector, err = election.NewFileLease(o.LeaseFile, id, o.LeaseTTL); err != nil {
+			log.Fatal(err)
+		}
+		log.Println("Waiting for leadership as ", id)
+		if err := elector.Campaign(context.Background()); err != nil {
+			log.Fatal("Leader election failed: ", err)
+		}
+		log.Println("Elected leader")
+		go func() {
+			<-elector.Lost()
+			log.Fatal("Lost leadership, exiting")
+		}()
+	}
+	recovered, err := s.Restore()
+	if err != nil {
+		log.Fatal("Unable to restore framework state: ", err)
+	}
+	if !recovered {
+		for _, spec := range persistent {
+			if err := s.CreateVolume(spec); err != nil {
+				log.Fatal(err)
+			}
+		}
+		for i := 0; i < o.MaxTasks; i++ {
+			vol := ""
+			if i < len(persistent) {
+				vol = persistent[i].ID
+			}
+			if _, err := s.Submit("", 0, labels, vol); err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
+	for _, id := range o.DestroyVolumes {
+		if err := s.DestroyVolume(id); err != nil {
+			log.Println("Unable to destroy volume: ", err)
+		}
+	}
+	// tasks are submitted at runtime through the task API
+	if o.API != "" {
+		s.KeepRunning = true
+		handler := o.Handler
+		if handler == nil {
+			handler = s.Handler()
+		}
+		go func() {
+			log.Fatal("Task API failed: ", http.ListenAndServe(o.API, handler))
+		}()
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		log.Println("Received ", sig, ", shutting down")
+		s.Shutdown(o.KillOnExit, o.KillGrace, o.ExitTimeout)
+	}()
+
+	<-s.Start()
+
+	// hand over to a standby right away
+	if elector != nil {
+		if err := elector.Resign(); err != nil {
+			log.Println("Unable to resign leadership: ", err)
+		}
+	}
+}
@@ -0,0 +1,284 @@
+// Package framework implements the scheduler side of a Mesos framework
+// shared by the scheduler binaries: it subscribes to the master, queues
+// tasks and launches them on offers, tracks their status, manages
+// reservations and persistent volumes, persists its state, and records
+// and replays sessions. Binaries complete the tasks it launches with
+// their command or executor through the Prepare hook.
+package framework
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/vladimirvivien/mesos-http/client"
+	"github.com/vladimirvivien/mesos-http/constraint"
+	"github.com/vladimirvivien/mesos-http/container"
+	"github.com/vladimirvivien/mesos-http/eventlog"
+	"github.com/vladimirvivien/mesos-http/mesos/mesos"
+	"github.com/vladimirvivien/mesos-http/mesos/sched"
+	"github.com/vladimirvivien/mesos-http/operation"
+	"github.com/vladimirvivien/mesos-http/queue"
+	"github.com/vladimirvivien/mesos-http/recordio"
+	"github.com/vladimirvivien/mesos-http/registry"
+	"github.com/vladimirvivien/mesos-http/reservation"
+	"github.com/vladimirvivien/mesos-http/store"
+	"github.com/vladimirvivien/mesos-http/volume"
+)
+
+// Scheduler represents a Mesos scheduler. Its exported fields are
+// settings, set before Start.
+type Scheduler struct {
+	// URIs and Container are given to the tasks submitted
+	URIs      []*mesos.CommandInfo_URI
+	Container *container.Spec
+	// Constraints place the tasks submitted
+	Constraints []*constraint.Constraint
+	// Reservations and Volumes are nil unless the framework
+	// reserves resources
+	Reservations *reservation.Manager
+	Volumes      *volume.Manager
+	// Store persists the framework state, if set
+	Store store.Store
+	// Recorder records the session, if set
+	Recorder *eventlog.Writer
+	// KeepRunning keeps the scheduler running once all tasks have
+	// terminated, for the tasks submitted later through its API
+	KeepRunning bool
+
+	// Prepare completes a task launched on the offer of plan, with
+	// its command or executor. An error leaves the task queued.
+	Prepare func(task *mesos.TaskInfo, pending *queue.Task, plan *operation.Plan) error
+	// Received handles the messages sent by executors, if set
+	Received func(msg *sched.Event_Message)
+	// Updated is called with each status update once recorded, if set
+	Updated func(status *mesos.TaskStatus)
+
+	framework    *mesos.FrameworkInfo
+	taskLaunched uint64 // atomic
+	taskFinished uint64 // atomic
+	queue        *queue.Queue
+	registry     *registry.Registry
+	persistMu    sync.Mutex
+	taskSeq      uint64
+	epoch        int64
+
+	client     *client.Client
+	cpuPerTask float64
+	memPerTask float64
+
+	placements map[string]constraint.Placement
+	mu         sync.Mutex
+
+	stream      *http.Response
+	streamMu    sync.Mutex
+	closing     chan struct{}
+	closingOnce sync.Once
+
+	shutdownMu   sync.Mutex
+	shutdownDone chan struct{}
+
+	replay    []*eventlog.Entry
+	replaying bool
+	// handled is signaled once each replayed event is handled
+	handled chan struct{}
+
+	events   chan *sched.Event
+	doneChan chan struct{}
+}
+
+// New returns a scheduler of the framework fw subscribing to master
+func New(master string, fw *mesos.FrameworkInfo) *Scheduler {
+	return &Scheduler{
+		client:     client.New(master, "/api/v1/scheduler"),
+		framework:  fw,
+		cpuPerTask: 1,
+		memPerTask: 128,
+		epoch:      time.Now().Unix(),
+		queue:      queue.New(),
+		registry:   registry.New(),
+		placements: make(map[string]constraint.Placement),
+		closing:    make(chan struct{}),
+		events:     make(chan *sched.Event),
+		doneChan:   make(chan struct{}),
+	}
+}
+
+// Framework returns the framework info
+func (s *Scheduler) Framework() *mesos.FrameworkInfo {
+	return s.framework
+}
+
+// FrameworkID returns the ID of the framework, nil until subscribed
+// or restored
+func (s *Scheduler) FrameworkID() *mesos.FrameworkID {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.framework.GetId()
+}
+
+func (s *Scheduler) setFrameworkID(id *mesos.FrameworkID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.framework.Id = id
+}
+
+// Registry returns the registry of the launched tasks
+func (s *Scheduler) Registry() *registry.Registry {
+	return s.registry
+}
+
+// Start starts the scheduler and subscribes to event stream
+// returns a channel to wait for completion.
+func (s *Scheduler) Start() <-chan struct{} {
+	if s.replaying {
+		go s.replayEvents()
+	} else {
+		s.recordSession()
+		if err := s.subscribe(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	go s.handleEvents()
+	return s.doneChan
+}
+
+// stop closes the event stream; handleEvents returns once
+// the remaining events are drained.
+func (s *Scheduler) stop() {
+	s.disconnect()
+}
+
+// Send sends a call to the master, or only records it during a replay
+func (s *Scheduler) Send(call *sched.Call) (*http.Response, error) {
+	payload, err := proto.Marshal(call)
+	if err != nil {
+		return nil, err
+	}
+	s.record(eventlog.Call, payload)
+	if s.replaying {
+		return replayed(call), nil
+	}
+	return s.client.Send(payload)
+}
+
+// Subscribe subscribes the scheduler to the Mesos cluster.
+// It keeps the http connection opens with the Master to stream
+// subsequent events.
+func (s *Scheduler) subscribe() error {
+	call := &sched.Call{
+		Type: sched.Call_SUBSCRIBE.Enum(),
+		Subscribe: &sched.Call_Subscribe{
+			FrameworkInfo: s.framework,
+		},
+	}
+
+	resp, err := s.Send(call)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Subscribe with unexpected response status: %d", resp.StatusCode)
+	}
+	log.Println("Mesos-Stream-Id:", s.client.StreamID)
+
+	s.streamMu.Lock()
+	s.stream = resp
+	s.streamMu.Unlock()
+	go s.qEvents(resp)
+
+	return nil
+}
+
+func (s *Scheduler) qEvents(resp *http.Response) {
+	defer func() {
+		resp.Body.Close()
+		close(s.events)
+	}()
+	reader := recordio.NewReader(resp.Body)
+	for {
+		data, err := reader.Read()
+		if err != nil {
+			if err != io.EOF && !s.ShuttingDown() {
+				log.Println("Event stream failed: ", err)
+			}
+			return
+		}
+		s.record(eventlog.Event, data)
+
+		event := new(sched.Event)
+		if err := json.Unmarshal(data, event); err != nil {
+			log.Println("Unable to decode event: ", err)
+			continue
+		}
+		s.events <- event
+	}
+}
+
+func (s *Scheduler) handleEvents() {
+	defer close(s.doneChan)
+	defer s.awaitShutdown()
+	for ev := range s.events {
+		switch ev.GetType() {
+
+		case sched.Event_SUBSCRIBED:
+			sub := ev.GetSubscribed()
+			s.setFrameworkID(sub.FrameworkId)
+			log.Println("Subscribed: FrameworkID: ", sub.FrameworkId.GetValue())
+			s.persist()
+			s.dispatch(s.reconcile)
+
+		case sched.Event_OFFERS:
+			offers := ev.GetOffers().GetOffers()
+			log.Println("Received ", len(offers), " offers ")
+			s.dispatch(func() { s.offers(offers) })
+
+		case sched.Event_RESCIND:
+			log.Println("Received rescind offers")
+
+		case sched.Event_UPDATE:
+			status := ev.GetUpdate().GetStatus()
+			s.dispatch(func() { s.status(status) })
+
+		case sched.Event_MESSAGE:
+			log.Println("Received message event")
+			if s.Received != nil {
+				s.Received(ev.GetMessage())
+			}
+
+		case sched.Event_FAILURE:
+			log.Println("Received failure event")
+			fail := ev.GetFailure()
+			if fail.ExecutorId != nil {
+				log.Println(
+					"Executor ", fail.ExecutorId.GetValue(), " terminated ",
+					" with status ", fail.GetStatus(),
+					" on agent ", fail.GetAgentId().GetValue(),
+				)
+			} else {
+				if fail.GetAgentId() != nil {
+					log.Println("Agent ", fail.GetAgentId().GetValue(), " failed ")
+					if s.Reservations != nil {
+						s.Reservations.Forget(fail.GetAgentId().GetValue())
+					}
+				}
+			}
+
+		case sched.Event_ERROR:
+			err := ev.GetError().GetMessage()
+			log.Println(err)
+
+		case sched.Event_HEARTBEAT:
+			log.Println("HEARTBEAT")
+		}
+
+		if s.handled != nil {
+			s.handled <- struct{}{}
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package framework
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/vladimirvivien/mesos-http/constraint"
+	"github.com/vladimirvivien/mesos-http/mesos/mesos"
+	"github.com/vladimirvivien/mesos-http/mesos/sched"
+	"github.com/vladimirvivien/mesos-http/mesostest"
+	"github.com/vladimirvivien/mesos-http/store"
+)
+
+func frameworkInfo() *mesos.FrameworkInfo {
+	return &mesos.FrameworkInfo{User: proto.String("u"), Name: proto.String("f")}
+}
+
+func waitFor(t *testing.T, master *mesostest.Master, typ sched.Call_Type, n int) []*sched.Call {
+	calls, err := master.WaitForCall(typ, n, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return calls
+}
+
+// launched returns the IDs of the tasks launched by an accept call
+func launched(call *sched.Call) []string {
+	var ids []string
+	for _, op := range call.GetAccept().GetOperations() {
+		for _, task := range op.GetLaunch().GetTaskInfos() {
+			ids = append(ids, task.GetTaskId().GetValue())
+		}
+	}
+	return ids
+}
+
+func closed(done <-chan struct{}, timeout time.Duration) bool {
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+func TestLaunch(t *testing.T) {
+	master := mesostest.NewMaster()
+	defer master.Close()
+	// room for two tasks of 1 cpu and 128 MB
+	master.Script(mesostest.OffersEvent(mesostest.Offer("o1", "a1", "h1", 2, 300)))
+
+	s := New(master.Addr(), frameworkInfo())
+	var ids []string
+	for i := 0; i < 3; i++ {
+		id, err := s.Submit("", 0, nil, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	done := s.Start()
+
+	accepts := waitFor(t, master, sched.Call_ACCEPT, 1)
+	if got := launched(accepts[0]); len(got) != 2 || got[0] != ids[0] || got[1] != ids[1] {
+		t.Fatalf("first offer launched %v, want %v", got, ids[:2])
+	}
+	if id := accepts[0].GetFrameworkId().GetValue(); id != master.FrameworkID().GetValue() {
+		t.Errorf("accept of framework %q, want %q", id, master.FrameworkID().GetValue())
+	}
+
+	// too small an offer is declined
+	master.Send(mesostest.OffersEvent(mesostest.Offer("o2", "a2", "h2", 0.5, 1024)))
+	accepts = waitFor(t, master, sched.Call_ACCEPT, 2)
+	if ops := accepts[1].GetAccept().GetOperations(); len(ops) != 0 {
+		t.Fatalf("small offer accepted with %v", ops)
+	}
+
+	master.Send(mesostest.OffersEvent(mesostest.Offer("o3", "a2", "h2", 1, 128)))
+	accepts = waitFor(t, master, sched.Call_ACCEPT, 3)
+	if got := launched(accepts[2]); len(got) != 1 || got[0] != ids[2] {
+		t.Fatalf("third offer launched %v, want %s", got, ids[2])
+	}
+
+	// every update carrying a UUID is acknowledged; the scheduler
+	// stops once all tasks finished
+	agents := map[string]string{ids[0]: "a1", ids[1]: "a1", ids[2]: "a2"}
+	for _, id := range ids {
+		master.Send(mesostest.UpdateEvent(mesostest.Status(id, agents[id], mesos.TaskState_TASK_RUNNING)))
+	}
+	// updates are handled concurrently, acknowledged in any order
+	acked := make(map[string]bool)
+	for _, ack := range waitFor(t, master, sched.Call_ACKNOWLEDGE, 3) {
+		acked[ack.GetAcknowledge().GetTaskId().GetValue()] = true
+	}
+	for _, id := range ids {
+		if !acked[id] {
+			t.Errorf("update of %s not acknowledged", id)
+		}
+	}
+	if n := len(s.Registry().Active()); n != 3 {
+		t.Fatalf("%d active tasks, want 3", n)
+	}
+	for _, id := range ids {
+		master.Send(mesostest.UpdateEvent(mesostest.Status(id, agents[id], mesos.TaskState_TASK_FINISHED)))
+	}
+	if !closed(done, 5*time.Second) {
+		t.Fatal("scheduler did not stop once all tasks finished")
+	}
+	if n := len(master.CallsOf(sched.Call_ACKNOWLEDGE)); n != 6 {
+		t.Errorf("%d acknowledgements, want 6", n)
+	}
+}
+
+func TestAcceptRejected(t *testing.T) {
+	master := mesostest.NewMaster()
+	defer master.Close()
+	master.Reject(sched.Call_ACCEPT, http.StatusServiceUnavailable)
+	master.Script(mesostest.OffersEvent(mesostest.Offer("o1", "a1", "h1", 4, 1024)))
+
+	s := New(master.Addr(), frameworkInfo())
+	unique, err := constraint.Parse("hostname:UNIQUE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Constraints = []*constraint.Constraint{unique}
+	id, _ := s.Submit("", 0, nil, "")
+	done := s.Start()
+
+	// the task of the rejected launch is queued again, neither
+	// registered nor placed
+	accepts := waitFor(t, master, sched.Call_ACCEPT, 1)
+	if got := launched(accepts[0]); len(got) != 1 || got[0] != id {
+		t.Fatalf("launched %v, want %s", got, id)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for s.queue.Len() != 1 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, ok := s.queue.Get(id); !ok {
+		t.Fatalf("task %s not requeued", id)
+	}
+	if _, ok := s.registry.Get(id); ok {
+		t.Errorf("task %s left registered", id)
+	}
+	if n := len(s.placed()); n != 0 {
+		t.Errorf("%d placements left", n)
+	}
+
+	// and launched on the next offer, on the same host
+	master.Reject(sched.Call_ACCEPT, 0)
+	master.Send(mesostest.OffersEvent(mesostest.Offer("o2", "a1", "h1", 4, 1024)))
+	accepts = waitFor(t, master, sched.Call_ACCEPT, 2)
+	if got := launched(accepts[1]); len(got) != 1 || got[0] != id {
+		t.Fatalf("relaunched %v, want %s", got, id)
+	}
+
+	s.Shutdown(false, 0, 0)
+	<-done
+}
+
+func TestConstraintPlacement(t *testing.T) {
+	master := mesostest.NewMaster()
+	defer master.Close()
+	master.Script(mesostest.OffersEvent(mesostest.Offer("o1", "a1", "h1", 4, 1024)))
+
+	s := New(master.Addr(), frameworkInfo())
+	unique, err := constraint.Parse("hostname:UNIQUE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Constraints = []*constraint.Constraint{unique}
+	first, _ := s.Submit("", 0, nil, "")
+	second, _ := s.Submit("", 0, nil, "")
+	done := s.Start()
+
+	accepts := waitFor(t, master, sched.Call_ACCEPT, 1)
+	if got := launched(accepts[0]); len(got) != 1 || got[0] != first {
+		t.Fatalf("launched %v, want only %s", got, first)
+	}
+	master.Send(mesostest.OffersEvent(mesostest.Offer("o2", "a1", "h1", 3, 896)))
+	accepts = waitFor(t, master, sched.Call_ACCEPT, 2)
+	if got := launched(accepts[1]); len(got) != 0 {
+		t.Fatalf("launched %v next to %s", got, first)
+	}
+
+	// the host is free again once the first task terminated
+	master.Send(mesostest.UpdateEvent(mesostest.Status(first, "a1", mesos.TaskState_TASK_FINISHED)))
+	waitFor(t, master, sched.Call_ACKNOWLEDGE, 1)
+	master.Send(mesostest.OffersEvent(mesostest.Offer("o3", "a1", "h1", 4, 1024)))
+	accepts = waitFor(t, master, sched.Call_ACCEPT, 3)
+	if got := launched(accepts[2]); len(got) != 1 || got[0] != second {
+		t.Fatalf("launched %v, want %s", got, second)
+	}
+
+	s.Shutdown(false, 0, 0)
+	<-done
+}
+
+func TestFailover(t *testing.T) {
+	dir, err := ioutil.TempDir("", "state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fs, err := store.NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	master := mesostest.NewMaster()
+	defer master.Close()
+	master.Script(mesostest.OffersEvent(mesostest.Offer("o1", "a1", "h1", 1, 128)))
+
+	fw := frameworkInfo()
+	fw.FailoverTimeout = proto.Float64(60)
+	s := New(master.Addr(), fw)
+	s.Store = fs
+	id, _ := s.Submit("", 0, nil, "")
+	pending, _ := s.Submit("", 0, nil, "")
+	done := s.Start()
+	waitFor(t, master, sched.Call_ACCEPT, 1)
+	master.Send(mesostest.UpdateEvent(mesostest.Status(id, "a1", mesos.TaskState_TASK_RUNNING)))
+	waitFor(t, master, sched.Call_ACKNOWLEDGE, 1)
+
+	// with a task left running, the scheduler disconnects without
+	// tearing the framework down
+	s.Shutdown(false, 0, 0)
+	<-done
+	if n := len(master.CallsOf(sched.Call_TEARDOWN)); n != 0 {
+		t.Fatal("framework torn down on failover")
+	}
+
+	// a new instance resumes with the framework ID, its tasks and
+	// its queue, and reconciles the running task
+	fwID := master.FrameworkID().GetValue()
+	s = New(master.Addr(), frameworkInfo())
+	s.Store = fs
+	recovered, err := s.Restore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !recovered {
+		t.Fatal("state not recovered")
+	}
+	if _, ok := s.queue.Get(pending); !ok {
+		t.Errorf("pending task %s not restored", pending)
+	}
+	done = s.Start()
+	subscribes := waitFor(t, master, sched.Call_SUBSCRIBE, 2)
+	if got := subscribes[1].GetSubscribe().GetFrameworkInfo().GetId().GetValue(); got != fwID {
+		t.Errorf("resubscribed as %q, want %q", got, fwID)
+	}
+	reconciles := waitFor(t, master, sched.Call_RECONCILE, 1)
+	tasks := reconciles[0].GetReconcile().GetTasks()
+	if len(tasks) != 1 || tasks[0].GetTaskId().GetValue() != id {
+		t.Errorf("reconciled %v, want %s", tasks, id)
+	}
+
+	s.Shutdown(false, 0, 0)
+	<-done
+}
@@ -0,0 +1,122 @@
+package framework
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/vladimirvivien/mesos-http/mesos/sched"
+	"github.com/vladimirvivien/mesos-http/store"
+)
+
+// Shutdown stops the framework. No new tasks are launched once it
+// is called. When kill is set, active tasks are killed with the given
+// grace period and awaited up to timeout. The framework is then torn
+// down, unless tasks were left running and a failover timeout is
+// configured, in which case the scheduler only disconnects so that
+// a new instance can resubscribe and take the tasks over. The channel
+// returned by Start is closed only once Shutdown returns; later calls
+// wait for the first one.
+func (s *Scheduler) Shutdown(kill bool, grace, timeout time.Duration) {
+	s.shutdownMu.Lock()
+	if s.shutdownDone != nil {
+		s.shutdownMu.Unlock()
+		<-s.shutdownDone
+		return
+	}
+	s.shutdownDone = make(chan struct{})
+	s.shutdownMu.Unlock()
+	defer close(s.shutdownDone)
+
+	s.closingOnce.Do(func() { close(s.closing) })
+
+	if kill {
+		if _, err := s.killTasks(nil, grace); err != nil {
+			log.Println("Unable to kill tasks: ", err)
+		}
+		if !s.awaitTerminal(timeout) {
+			log.Println("Timed out waiting for ", len(s.registry.Active()), " tasks to terminate")
+		}
+	}
+
+	if s.framework.GetFailoverTimeout() > 0 && len(s.registry.Active()) > 0 {
+		log.Println("Disconnecting, framework fails over within ", s.framework.GetFailoverTimeout(), "s")
+		s.persist()
+	} else {
+		s.teardown()
+	}
+	s.disconnect()
+}
+
+// teardown unregisters the framework; the master kills all its
+// tasks and executors.
+func (s *Scheduler) teardown() {
+	id := s.FrameworkID()
+	if id == nil {
+		return
+	}
+	call := &sched.Call{
+		FrameworkId: id,
+		Type:        sched.Call_TEARDOWN.Enum(),
+	}
+	log.Println("Tearing down framework ", id.GetValue())
+	resp, err := s.Send(call)
+	if err != nil {
+		log.Println("Unable to send Teardown Call: ", err)
+		return
+	}
+	if resp.StatusCode != http.StatusAccepted {
+		log.Printf("Teardown call returned unexpected status: %d", resp.StatusCode)
+		return
+	}
+
+	// a new instance must register as a new framework
+	if s.Store != nil {
+		if err := store.Clear(s.Store); err != nil {
+			log.Println("Unable to clear framework state: ", err)
+		}
+	}
+}
+
+// awaitShutdown waits for Shutdown to return, if it was called
+func (s *Scheduler) awaitShutdown() {
+	s.shutdownMu.Lock()
+	done := s.shutdownDone
+	s.shutdownMu.Unlock()
+	if done != nil {
+		<-done
+	}
+}
+
+// disconnect closes the subscription stream
+func (s *Scheduler) disconnect() {
+	s.closingOnce.Do(func() { close(s.closing) })
+	s.streamMu.Lock()
+	defer s.streamMu.Unlock()
+	if s.stream != nil {
+		s.stream.Body.Close()
+		s.stream = nil
+	}
+}
+
+// awaitTerminal waits until no task is active or the timeout expires
+func (s *Scheduler) awaitTerminal(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for len(s.registry.Active()) > 0 {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return true
+}
+
+// ShuttingDown reports whether Shutdown was called
+func (s *Scheduler) ShuttingDown() bool {
+	select {
+	case <-s.closing:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,129 @@
+package framework
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/vladimirvivien/mesos-http/constraint"
+	"github.com/vladimirvivien/mesos-http/mesos/sched"
+	"github.com/vladimirvivien/mesos-http/registry"
+	"github.com/vladimirvivien/mesos-http/store"
+)
+
+// Restore reloads the framework ID, task registry, pending queue,
+// placements, reservations and volumes from the state store. It
+// returns true if any state was persisted, in which case the tasks of
+// the previous run are not to be queued again; with a framework ID,
+// the scheduler resubscribes with it.
+func (s *Scheduler) Restore() (bool, error) {
+	if s.Store == nil {
+		return false, nil
+	}
+	st, err := store.Load(s.Store)
+	if err != nil || st == nil {
+		return false, err
+	}
+	if err := st.RestoreRegistry(s.registry); err != nil {
+		return false, err
+	}
+	s.prune()
+	if err := st.RestoreQueue(s.queue); err != nil {
+		return false, err
+	}
+	// only the tasks still active constrain new placements
+	s.mu.Lock()
+	for _, task := range s.registry.Active() {
+		if p, ok := st.Placements[task.ID()]; ok {
+			s.placements[task.ID()] = p
+		}
+	}
+	s.mu.Unlock()
+	if s.Reservations != nil {
+		st.RestoreReservations(s.Reservations)
+	}
+	if s.Volumes != nil {
+		st.RestoreVolumes(s.Volumes)
+	}
+	id := st.GetFrameworkID()
+	s.setFrameworkID(id)
+	log.Println(
+		"Restored framework ", id.GetValue(),
+		" with ", len(s.registry.Active()), " active tasks and ",
+		s.queue.Len(), " pending tasks",
+	)
+	return true, nil
+}
+
+// prune drops the terminated tasks from the registry, so that the
+// persisted registry holds the active tasks only.
+func (s *Scheduler) prune() {
+	for _, task := range s.registry.Select(func(t *registry.Task) bool { return t.Terminal() }) {
+		s.registry.Remove(task.ID())
+	}
+}
+
+// persist saves the framework state to the state store, if any.
+// The state is saved as a whole, so a crash leaves either the
+// previous or the new state.
+func (s *Scheduler) persist() {
+	if s.Store == nil {
+		return
+	}
+	s.persistMu.Lock()
+	defer s.persistMu.Unlock()
+
+	st := new(store.State)
+	if id := s.FrameworkID(); id != nil {
+		st.SetFrameworkID(id)
+	}
+	if err := st.SetRegistry(s.registry); err != nil {
+		log.Println("Unable to save task registry: ", err)
+		return
+	}
+	st.SetQueue(s.queue)
+	s.mu.Lock()
+	st.Placements = make(map[string]constraint.Placement, len(s.placements))
+	for id, p := range s.placements {
+		st.Placements[id] = p
+	}
+	s.mu.Unlock()
+	if s.Reservations != nil {
+		st.SetReservations(s.Reservations)
+	}
+	if s.Volumes != nil {
+		st.SetVolumes(s.Volumes)
+	}
+	if err := store.Save(s.Store, st); err != nil {
+		log.Println("Unable to save framework state: ", err)
+	}
+}
+
+// reconcile asks the master for the latest state of all
+// active tasks known to the registry.
+func (s *Scheduler) reconcile() {
+	var tasks []*sched.Call_Reconcile_Task
+	for _, status := range s.registry.Statuses() {
+		tasks = append(tasks, &sched.Call_Reconcile_Task{
+			TaskId:  status.GetTaskId(),
+			AgentId: status.GetAgentId(),
+		})
+	}
+	if len(tasks) == 0 {
+		return
+	}
+	log.Println("Reconciling ", len(tasks), " tasks")
+
+	call := &sched.Call{
+		FrameworkId: s.FrameworkID(),
+		Type:        sched.Call_RECONCILE.Enum(),
+		Reconcile:   &sched.Call_Reconcile{Tasks: tasks},
+	}
+	resp, err := s.Send(call)
+	if err != nil {
+		log.Println("Unable to send Reconcile Call: ", err)
+		return
+	}
+	if resp.StatusCode != http.StatusAccepted {
+		log.Printf("Reconcile call returned unexpected status: %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,105 @@
+package framework
+
+import (
+	"log"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/vladimirvivien/mesos-http/mesos/mesos"
+	"github.com/vladimirvivien/mesos-http/mesos/sched"
+	"github.com/vladimirvivien/mesos-http/registry"
+)
+
+func (s *Scheduler) status(status *mesos.TaskStatus) {
+	if err := s.registry.Update(status); err != nil {
+		log.Println("Unable to record status update: ", err)
+	}
+
+	// kills requested through KillTask are expected, as are kills by
+	// a failing health check, reported unhealthy.
+	requested := false
+	if task, ok := s.registry.Get(status.GetTaskId().GetValue()); ok {
+		requested = !task.KillRequested.IsZero()
+	}
+	unhealthy := status.Healthy != nil && !status.GetHealthy()
+
+	// a terminated task no longer counts against the constraints
+	if registry.IsTerminal(status.GetState()) {
+		s.unplace(status.GetTaskId().GetValue())
+	}
+	s.prune()
+	s.persist()
+	if s.Updated != nil {
+		s.Updated(status)
+	}
+
+	if status.GetState() == mesos.TaskState_TASK_LOST ||
+		(status.GetState() == mesos.TaskState_TASK_KILLED && !requested && !unhealthy) ||
+		status.GetState() == mesos.TaskState_TASK_FAILED {
+		log.Fatal(
+			"Exiting because task ",
+			status.GetTaskId().GetValue(),
+			" is in unexpected statse ", status.GetState().String(),
+			" with reason ", status.GetReason().String(),
+			" from source ", status.GetSource().String(),
+			" with message ", status.GetMessage(),
+		)
+	}
+
+	// send ack
+	if status.GetUuid() != nil {
+		call := &sched.Call{
+			FrameworkId: s.FrameworkID(),
+			Type:        sched.Call_ACKNOWLEDGE.Enum(),
+			Acknowledge: &sched.Call_Acknowledge{
+				AgentId: status.GetAgentId(),
+				TaskId:  status.GetTaskId(),
+				Uuid:    status.GetUuid(),
+			},
+		}
+
+		// send call
+		resp, err := s.Send(call)
+		if err != nil {
+			log.Println("Unable to send Acknowledge Call: ", err)
+			return
+		}
+		if resp.StatusCode != http.StatusAccepted {
+			log.Printf("Acknowledge call returned unexpected status: %d", resp.StatusCode)
+		}
+	}
+
+	if status.GetState() == mesos.TaskState_TASK_ERROR {
+		atomic.AddUint64(&s.taskFinished, 1)
+		log.Println(
+			"Task ID ", status.TaskId.GetValue(),
+			" state = ", status.GetState().String(),
+			" message = ", status.GetMessage(),
+		)
+	}
+
+	if status.GetState() == mesos.TaskState_TASK_KILLING {
+		log.Println("Killing task: ", status.GetTaskId().GetValue())
+	}
+
+	if status.GetState() == mesos.TaskState_TASK_KILLED {
+		if unhealthy && !requested {
+			log.Println("Task ", status.GetTaskId().GetValue(), " killed after failing health checks")
+		} else {
+			log.Println("Killed task: ", status.GetTaskId().GetValue())
+		}
+		atomic.AddUint64(&s.taskFinished, 1)
+	}
+
+	if status.GetState() == mesos.TaskState_TASK_FINISHED {
+		log.Println("Finished task: ", status.GetTaskId().GetValue())
+		atomic.AddUint64(&s.taskFinished, 1)
+	}
+
+	// while shutting down, Shutdown closes the stream once done
+	if len(s.registry.Active()) == 0 && s.queue.Len() == 0 && !s.ShuttingDown() && !s.KeepRunning {
+		log.Println("Scheduler executed all tasks")
+		s.stop()
+	}
+
+}
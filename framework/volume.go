@@ -0,0 +1,82 @@
+package framework
+
+import (
+	"log"
+
+	"github.com/vladimirvivien/mesos-http/mesos/mesos"
+	"github.com/vladimirvivien/mesos-http/operation"
+	"github.com/vladimirvivien/mesos-http/queue"
+	"github.com/vladimirvivien/mesos-http/reservation"
+	"github.com/vladimirvivien/mesos-http/volume"
+)
+
+// planVolumes plans the persistent volumes destroyed and created on
+// an offer, drawing on the disk reserved in budget.
+func (s *Scheduler) planVolumes(plan *operation.Plan, budget *reservation.Budget) {
+	if s.Volumes == nil || s.ShuttingDown() {
+		return
+	}
+	agentID := plan.Offer().GetAgentId().GetValue()
+	create, destroy := s.Volumes.Plan(plan.Offer(), budget, s.used(agentID))
+	if len(destroy) > 0 {
+		log.Println("Destroying ", destroy, " on agent ", agentID)
+		if err := plan.Destroy(destroy...); err != nil {
+			log.Println("Unable to destroy volumes: ", err)
+		}
+	}
+	if len(create) > 0 {
+		log.Println("Creating ", create, " on agent ", agentID)
+		if err := plan.Create(create...); err != nil {
+			log.Println("Unable to create volumes: ", err)
+		}
+	}
+}
+
+// volumeFor returns the volume a pending task runs with, among those
+// offered or created by plan. It returns false if the task needs a
+// volume the plan does not hold.
+func (s *Scheduler) volumeFor(pending *queue.Task, plan *operation.Plan) (*mesos.Resource, bool) {
+	if pending.Volume == "" {
+		return nil, true
+	}
+	if s.Volumes == nil {
+		return nil, false
+	}
+	if v, ok := s.Volumes.Get(pending.Volume); !ok || v.State == volume.Destroying {
+		return nil, false
+	}
+	res := s.Volumes.Find(plan.Remaining(), pending.Volume)
+	return res, res != nil
+}
+
+// CreateVolume requests a persistent volume, created on the next
+// offer with enough reserved disk.
+func (s *Scheduler) CreateVolume(spec *volume.Spec) error {
+	if err := s.Volumes.Create(*spec); err != nil {
+		return err
+	}
+	s.persist()
+	return nil
+}
+
+// DestroyVolume requests the destruction of a persistent volume
+func (s *Scheduler) DestroyVolume(id string) error {
+	if err := s.Volumes.Destroy(id); err != nil {
+		return err
+	}
+	s.persist()
+	return nil
+}
+
+// ParseVolumes parses volume specs <id:size_mb:container_path>
+func ParseVolumes(values []string) ([]*volume.Spec, error) {
+	var specs []*volume.Spec
+	for _, v := range values {
+		spec, err := volume.ParseSpec(v)
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
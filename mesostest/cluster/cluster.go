@@ -0,0 +1,559 @@
+package cluster
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/vladimirvivien/mesos-http/mesos/mesos"
+	"github.com/vladimirvivien/mesos-http/mesos/sched"
+	"github.com/vladimirvivien/mesos-http/mesostest"
+	"github.com/vladimirvivien/mesos-http/operation"
+	"github.com/vladimirvivien/mesos-http/registry"
+)
+
+// AgentSpec describes a virtual agent. Its resources are
+// unreserved; disk may be reserved and hold persistent volumes.
+type AgentSpec struct {
+	ID         string
+	Hostname   string
+	Cpus       float64
+	Mem        float64
+	Disk       float64
+	Attributes []*mesos.Attribute
+}
+
+// Config configures a simulated cluster
+type Config struct {
+	Agents []AgentSpec
+
+	// OfferInterval is how often free resources are offered
+	OfferInterval time.Duration
+
+	// StartLatency is the delay from launch to TASK_RUNNING,
+	// RunTime the delay from TASK_RUNNING to a terminal state.
+	StartLatency time.Duration
+	RunTime      time.Duration
+
+	// FailureRate is the probability, between 0 and 1, that
+	// a task ends with TASK_FAILED instead of TASK_FINISHED.
+	FailureRate float64
+
+	// Seed seeds task failures, for reproducible runs
+	Seed int64
+}
+
+// Agents returns n agent specs with the same resources, spread
+// over the given number of racks using a "rack" text attribute.
+func Agents(n int, cpus, mem float64, racks int) []AgentSpec {
+	agents := make([]AgentSpec, n)
+	for i := range agents {
+		agents[i] = AgentSpec{
+			ID:       fmt.Sprintf("agent-%d", i+1),
+			Hostname: fmt.Sprintf("host-%d", i+1),
+			Cpus:     cpus,
+			Mem:      mem,
+		}
+		if racks > 0 {
+			agents[i].Attributes = []*mesos.Attribute{{
+				Name: proto.String("rack"),
+				Type: mesos.Value_TEXT.Enum(),
+				Text: &mesos.Value_Text{Value: proto.String(fmt.Sprintf("rack-%d", i%racks+1))},
+			}}
+		}
+	}
+	return agents
+}
+
+// agent holds the resources of a virtual agent that are neither
+// used by tasks nor offered, along with its running executors.
+type agent struct {
+	spec      AgentSpec
+	free      []*mesos.Resource
+	offer     string
+	offered   []*mesos.Resource
+	executors map[string]*executor
+}
+
+// executor holds the resources of an executor until its last
+// task terminates.
+type executor struct {
+	resources []*mesos.Resource
+	tasks     int
+}
+
+type task struct {
+	info      *mesos.TaskInfo
+	agent     *agent
+	resources []*mesos.Resource
+	state     mesos.TaskState
+	timer     *time.Timer
+}
+
+// Cluster simulates a Mesos master with virtual agents. A simple
+// allocator offers each agent's free resources to the subscribed
+// framework. Accepted offers may reserve and unreserve resources,
+// create and destroy persistent volumes and launch tasks, which go
+// through TASK_RUNNING to TASK_FINISHED or TASK_FAILED after the
+// configured latencies.
+type Cluster struct {
+	Master *mesostest.Master
+
+	config Config
+	rand   *rand.Rand
+
+	mu      sync.Mutex
+	agents  map[string]*agent
+	offers  map[string]*agent
+	tasks   map[string]*task
+	offerID int
+	done    chan struct{}
+	closing sync.Once
+}
+
+// New starts a simulated cluster on a local port
+func New(config Config) *Cluster {
+	return newCluster(mesostest.NewMaster(), config)
+}
+
+// NewAt starts a simulated cluster listening on addr, so it can
+// drive the sched-cmd and sched-exec binaries.
+func NewAt(addr string, config Config) (*Cluster, error) {
+	m, err := mesostest.NewMasterAt(addr)
+	if err != nil {
+		return nil, err
+	}
+	return newCluster(m, config), nil
+}
+
+func newCluster(m *mesostest.Master, config Config) *Cluster {
+	if config.OfferInterval <= 0 {
+		config.OfferInterval = time.Second
+	}
+	c := &Cluster{
+		Master: m,
+		config: config,
+		rand:   rand.New(rand.NewSource(config.Seed)),
+		agents: make(map[string]*agent),
+		offers: make(map[string]*agent),
+		tasks:  make(map[string]*task),
+		done:   make(chan struct{}),
+	}
+	for _, spec := range config.Agents {
+		a := &agent{spec: spec, executors: make(map[string]*executor)}
+		for _, r := range []struct {
+			name  string
+			value float64
+		}{{"cpus", spec.Cpus}, {"mem", spec.Mem}, {"disk", spec.Disk}} {
+			if r.value > 0 {
+				a.free = append(a.free, mesostest.Scalar(r.name, r.value))
+			}
+		}
+		c.agents[spec.ID] = a
+	}
+	m.OnCall = c.handle
+	go c.allocate()
+	return c
+}
+
+// Addr returns the master address as <ip:port>
+func (c *Cluster) Addr() string {
+	return c.Master.Addr()
+}
+
+// Close stops the allocator, the running tasks and the master.
+// It may be called more than once.
+func (c *Cluster) Close() {
+	c.closing.Do(func() {
+		close(c.done)
+		c.mu.Lock()
+		for _, t := range c.tasks {
+			if t.timer != nil {
+				t.timer.Stop()
+			}
+		}
+		c.mu.Unlock()
+		c.Master.Close()
+	})
+}
+
+// TaskState returns the simulated state of a task
+func (c *Cluster) TaskState(taskID string) (mesos.TaskState, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t, ok := c.tasks[taskID]
+	if !ok {
+		return 0, false
+	}
+	return t.state, true
+}
+
+// Tasks returns the number of tasks in each state
+func (c *Cluster) Tasks() map[mesos.TaskState]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	counts := make(map[mesos.TaskState]int)
+	for _, t := range c.tasks {
+		counts[t.state]++
+	}
+	return counts
+}
+
+// Resources returns the resources of an agent not used by tasks,
+// with their reservations and volumes.
+func (c *Cluster) Resources(agentID string) []*mesos.Resource {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	a, ok := c.agents[agentID]
+	if !ok {
+		return nil
+	}
+	return merged(append(append([]*mesos.Resource(nil), a.free...), a.offered...))
+}
+
+// allocate periodically offers free resources of agents that
+// have no outstanding offer.
+func (c *Cluster) allocate() {
+	ticker := time.NewTicker(c.config.OfferInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			c.offer()
+		}
+	}
+}
+
+func (c *Cluster) offer() {
+	fwID := c.Master.FrameworkID()
+	if fwID == nil || c.Master.StreamID() == "" {
+		return
+	}
+
+	c.mu.Lock()
+	var offers []*mesos.Offer
+	for _, a := range c.agents {
+		if a.offer != "" || len(a.free) == 0 {
+			continue
+		}
+		c.offerID++
+		id := fmt.Sprintf("offer-%d", c.offerID)
+		a.offer, a.offered, a.free = id, merged(a.free), nil
+		c.offers[id] = a
+
+		offers = append(offers, &mesos.Offer{
+			Id:          &mesos.OfferID{Value: proto.String(id)},
+			FrameworkId: fwID,
+			AgentId:     &mesos.AgentID{Value: proto.String(a.spec.ID)},
+			Hostname:    proto.String(a.spec.Hostname),
+			Resources:   a.offered,
+			Attributes:  a.spec.Attributes,
+		})
+	}
+	c.mu.Unlock()
+
+	if len(offers) > 0 {
+		if err := c.Master.Send(mesostest.OffersEvent(offers...)); err != nil {
+			log.Println("Unable to send offers: ", err)
+		}
+	}
+}
+
+// handle reacts to the calls received by the master
+func (c *Cluster) handle(call *sched.Call) {
+	switch call.GetType() {
+	case sched.Call_ACCEPT:
+		c.accept(call.GetAccept())
+	case sched.Call_DECLINE:
+		c.release(call.GetDecline().GetOfferIds())
+	case sched.Call_KILL:
+		c.kill(call.GetKill().GetTaskId().GetValue())
+	case sched.Call_SHUTDOWN:
+		c.shutdown(call.GetShutdown())
+	case sched.Call_RECONCILE:
+		c.reconcile(call.GetReconcile())
+	case sched.Call_TEARDOWN:
+		c.teardown()
+	}
+}
+
+// release takes outstanding offers back so they are made again
+func (c *Cluster) release(ids []*mesos.OfferID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, id := range ids {
+		if a, ok := c.offers[id.GetValue()]; ok {
+			a.free = append(a.free, a.offered...)
+			a.offer, a.offered = "", nil
+			delete(c.offers, id.GetValue())
+		}
+	}
+}
+
+// accept applies the operations on an offer in order, as the master
+// does: invalid operations are dropped, tasks of an invalid launch
+// reported TASK_ERROR. What the operations leave of the offer is
+// free again.
+func (c *Cluster) accept(accept *sched.Call_Accept) {
+	c.mu.Lock()
+	var launched []*task
+	var invalid []*mesos.TaskStatus
+	for _, id := range accept.GetOfferIds() {
+		a, ok := c.offers[id.GetValue()]
+		if !ok {
+			continue
+		}
+		plan := operation.New(&mesos.Offer{
+			AgentId:   &mesos.AgentID{Value: proto.String(a.spec.ID)},
+			Resources: a.offered,
+		})
+		for _, op := range accept.GetOperations() {
+			var err error
+			switch op.GetType() {
+			case mesos.Offer_Operation_RESERVE:
+				err = plan.Reserve(op.GetReserve().GetResources()...)
+			case mesos.Offer_Operation_UNRESERVE:
+				err = plan.Unreserve(op.GetUnreserve().GetResources()...)
+			case mesos.Offer_Operation_CREATE:
+				if err = c.checkVolumes(a, plan, op.GetCreate().GetVolumes()); err == nil {
+					err = plan.Create(op.GetCreate().GetVolumes()...)
+				}
+			case mesos.Offer_Operation_DESTROY:
+				err = plan.Destroy(op.GetDestroy().GetVolumes()...)
+			case mesos.Offer_Operation_LAUNCH:
+				tasks, err := c.launch(a, plan, op.GetLaunch().GetTaskInfos())
+				if err != nil {
+					log.Println("Dropping launch on offer ", id.GetValue(), ": ", err)
+					for _, info := range op.GetLaunch().GetTaskInfos() {
+						invalid = append(invalid, status(info.GetTaskId(), a.spec.ID, mesos.TaskState_TASK_ERROR,
+							mesos.TaskStatus_SOURCE_MASTER, mesos.TaskStatus_REASON_TASK_INVALID))
+					}
+				}
+				launched = append(launched, tasks...)
+				continue
+			default:
+				err = fmt.Errorf("unsupported operation")
+			}
+			if err != nil {
+				log.Println("Dropping ", op.GetType(), " on offer ", id.GetValue(), ": ", err)
+			}
+		}
+		a.offered = plan.Remaining()
+	}
+	c.mu.Unlock()
+
+	c.release(accept.GetOfferIds())
+	for _, st := range invalid {
+		c.Master.Send(mesostest.UpdateEvent(st))
+	}
+	for _, t := range launched {
+		c.schedule(t, c.config.StartLatency, mesos.TaskState_TASK_RUNNING)
+	}
+}
+
+// checkVolumes rejects volumes whose persistence ID is already in use
+// on the agent, offered, free or held by an active task, as the master
+// does. It is called with c.mu held.
+func (c *Cluster) checkVolumes(a *agent, plan *operation.Plan, volumes []*mesos.Resource) error {
+	inUse := make(map[string]bool)
+	resources := append(append([]*mesos.Resource(nil), a.free...), plan.Remaining()...)
+	for _, t := range c.tasks {
+		if t.agent == a && !registry.IsTerminal(t.state) {
+			resources = append(resources, t.resources...)
+		}
+	}
+	for _, res := range resources {
+		if id := res.GetDisk().GetPersistence().GetId(); id != "" {
+			inUse[id] = true
+		}
+	}
+	for _, vol := range volumes {
+		if id := vol.GetDisk().GetPersistence().GetId(); inUse[id] {
+			return fmt.Errorf("persistence ID %s is already in use", id)
+		}
+	}
+	return nil
+}
+
+// launch plans the launch of tasks on an agent and tracks them. The
+// resources of executors already running on the agent are not taken
+// again. It is called with c.mu held.
+func (c *Cluster) launch(a *agent, plan *operation.Plan, infos []*mesos.TaskInfo) ([]*task, error) {
+	var planned []*mesos.TaskInfo
+	for _, info := range infos {
+		if _, dup := c.tasks[info.GetTaskId().GetValue()]; dup {
+			return nil, fmt.Errorf("task %s already exists", info.GetTaskId().GetValue())
+		}
+		if _, running := a.executors[info.GetExecutor().GetExecutorId().GetValue()]; running {
+			info = proto.Clone(info).(*mesos.TaskInfo)
+			info.Executor.Resources = nil
+		}
+		planned = append(planned, info)
+	}
+	if err := plan.Launch(planned...); err != nil {
+		return nil, err
+	}
+
+	var tasks []*task
+	for i, info := range infos {
+		if exec := info.GetExecutor(); exec != nil {
+			e, ok := a.executors[exec.GetExecutorId().GetValue()]
+			if !ok {
+				e = &executor{resources: planned[i].GetExecutor().GetResources()}
+				a.executors[exec.GetExecutorId().GetValue()] = e
+			}
+			e.tasks++
+		}
+		t := &task{info: info, agent: a, resources: info.GetResources(), state: mesos.TaskState_TASK_STAGING}
+		c.tasks[info.GetTaskId().GetValue()] = t
+		tasks = append(tasks, t)
+	}
+	return tasks, nil
+}
+
+// schedule moves the task to state after delay
+func (c *Cluster) schedule(t *task, delay time.Duration, state mesos.TaskState) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t.timer = time.AfterFunc(delay, func() {
+		c.transition(t, state)
+	})
+}
+
+func (c *Cluster) transition(t *task, state mesos.TaskState) {
+	c.mu.Lock()
+	if registry.IsTerminal(t.state) {
+		c.mu.Unlock()
+		return
+	}
+	t.state = state
+	if registry.IsTerminal(state) {
+		c.free(t)
+	}
+	next := mesos.TaskState_TASK_FINISHED
+	if c.rand.Float64() < c.config.FailureRate {
+		next = mesos.TaskState_TASK_FAILED
+	}
+	c.mu.Unlock()
+
+	c.Master.Send(mesostest.UpdateEvent(status(
+		t.info.GetTaskId(), t.agent.spec.ID, state,
+		mesos.TaskStatus_SOURCE_EXECUTOR, 0,
+	)))
+	if state == mesos.TaskState_TASK_RUNNING {
+		c.schedule(t, c.config.RunTime, next)
+	}
+}
+
+// free returns the resources of a terminated task to its agent, and
+// those of its executor once the executor has no task left. It is
+// called with c.mu held.
+func (c *Cluster) free(t *task) {
+	a := t.agent
+	a.free = append(a.free, t.resources...)
+	execID := t.info.GetExecutor().GetExecutorId().GetValue()
+	if e, ok := a.executors[execID]; ok {
+		if e.tasks--; e.tasks == 0 {
+			a.free = append(a.free, e.resources...)
+			delete(a.executors, execID)
+		}
+	}
+}
+
+func (c *Cluster) kill(taskID string) {
+	c.mu.Lock()
+	t, ok := c.tasks[taskID]
+	if ok && t.timer != nil {
+		t.timer.Stop()
+	}
+	c.mu.Unlock()
+	if !ok {
+		return
+	}
+	c.transition(t, mesos.TaskState_TASK_KILLED)
+}
+
+func (c *Cluster) shutdown(shutdown *sched.Call_Shutdown) {
+	c.mu.Lock()
+	var ids []string
+	for id, t := range c.tasks {
+		if t.agent.spec.ID == shutdown.GetAgentId().GetValue() &&
+			t.info.GetExecutor().GetExecutorId().GetValue() == shutdown.GetExecutorId().GetValue() {
+			ids = append(ids, id)
+		}
+	}
+	c.mu.Unlock()
+	for _, id := range ids {
+		c.kill(id)
+	}
+}
+
+func (c *Cluster) teardown() {
+	c.mu.Lock()
+	var ids []string
+	for id := range c.tasks {
+		ids = append(ids, id)
+	}
+	c.mu.Unlock()
+	for _, id := range ids {
+		c.kill(id)
+	}
+	c.Master.Disconnect()
+}
+
+// reconcile sends the latest state of the requested tasks, or of
+// all tasks for an implicit reconciliation.
+func (c *Cluster) reconcile(reconcile *sched.Call_Reconcile) {
+	c.mu.Lock()
+	var statuses []*mesos.TaskStatus
+	if len(reconcile.GetTasks()) == 0 {
+		for _, t := range c.tasks {
+			if !registry.IsTerminal(t.state) {
+				statuses = append(statuses, reconciled(t))
+			}
+		}
+	}
+	for _, rt := range reconcile.GetTasks() {
+		if t, ok := c.tasks[rt.GetTaskId().GetValue()]; ok {
+			statuses = append(statuses, reconciled(t))
+			continue
+		}
+		st := status(rt.GetTaskId(), rt.GetAgentId().GetValue(), mesos.TaskState_TASK_LOST,
+			mesos.TaskStatus_SOURCE_MASTER, mesos.TaskStatus_REASON_RECONCILIATION)
+		st.Uuid = nil
+		statuses = append(statuses, st)
+	}
+	c.mu.Unlock()
+
+	for _, st := range statuses {
+		c.Master.Send(mesostest.UpdateEvent(st))
+	}
+}
+
+func reconciled(t *task) *mesos.TaskStatus {
+	st := status(t.info.GetTaskId(), t.agent.spec.ID, t.state,
+		mesos.TaskStatus_SOURCE_MASTER, mesos.TaskStatus_REASON_RECONCILIATION)
+	st.Uuid = nil
+	return st
+}
+
+func status(id *mesos.TaskID, agentID string, state mesos.TaskState,
+	source mesos.TaskStatus_Source, reason mesos.TaskStatus_Reason) *mesos.TaskStatus {
+	st := mesostest.Status(id.GetValue(), agentID, state)
+	st.Source = source.Enum()
+	if reason != 0 {
+		st.Reason = reason.Enum()
+	}
+	st.Timestamp = proto.Float64(float64(time.Now().UnixNano()) / 1e9)
+	return st
+}
+
+// merged returns resources with those of the same kind added up
+func merged(resources []*mesos.Resource) []*mesos.Resource {
+	return operation.New(&mesos.Offer{Resources: resources}).Remaining()
+}
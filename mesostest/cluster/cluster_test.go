@@ -0,0 +1,310 @@
+package cluster
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/vladimirvivien/mesos-http/client"
+	"github.com/vladimirvivien/mesos-http/mesos/mesos"
+	"github.com/vladimirvivien/mesos-http/mesos/sched"
+	"github.com/vladimirvivien/mesos-http/recordio"
+)
+
+const timeout = 5 * time.Second
+
+// framework is a bare scheduler subscribed with a cluster
+type framework struct {
+	t      *testing.T
+	client *client.Client
+	id     *mesos.FrameworkID
+	events chan *sched.Event
+	// skipped holds the events received while waiting for others
+	skipped []*sched.Event
+}
+
+func startCluster(t *testing.T) *Cluster {
+	c := New(Config{
+		Agents:        []AgentSpec{{ID: "a1", Hostname: "h1", Cpus: 2, Mem: 256, Disk: 100}},
+		OfferInterval: 10 * time.Millisecond,
+		StartLatency:  10 * time.Millisecond,
+		RunTime:       10 * time.Millisecond,
+	})
+	t.Cleanup(c.Close)
+	return c
+}
+
+func subscribe(t *testing.T, c *Cluster) *framework {
+	f := &framework{
+		t:      t,
+		client: client.New(c.Addr(), "/api/v1/scheduler"),
+		events: make(chan *sched.Event, 100),
+	}
+	resp := f.send(&sched.Call{
+		Type: sched.Call_SUBSCRIBE.Enum(),
+		Subscribe: &sched.Call_Subscribe{
+			FrameworkInfo: &mesos.FrameworkInfo{
+				User: proto.String("u"),
+				Name: proto.String("f"),
+				Role: proto.String("r"),
+			},
+		},
+	})
+	go func() {
+		defer resp.Body.Close()
+		reader := recordio.NewReader(resp.Body)
+		for {
+			data, err := reader.Read()
+			if err != nil {
+				return
+			}
+			event := new(sched.Event)
+			if err := json.Unmarshal(data, event); err == nil {
+				f.events <- event
+			}
+		}
+	}()
+	f.id = f.next(sched.Event_SUBSCRIBED).GetSubscribed().GetFrameworkId()
+	return f
+}
+
+func (f *framework) send(call *sched.Call) *http.Response {
+	call.FrameworkId = f.id
+	payload, err := proto.Marshal(call)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	resp, err := f.client.Send(payload)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+		f.t.Fatalf("%s answered %s", call.GetType(), resp.Status)
+	}
+	return resp
+}
+
+// next returns the next event of the given type
+func (f *framework) next(typ sched.Event_Type) *sched.Event {
+	for i, event := range f.skipped {
+		if event.GetType() == typ {
+			f.skipped = append(f.skipped[:i:i], f.skipped[i+1:]...)
+			return event
+		}
+	}
+	deadline := time.After(timeout)
+	for {
+		select {
+		case event := <-f.events:
+			if event.GetType() == typ {
+				return event
+			}
+			f.skipped = append(f.skipped, event)
+		case <-deadline:
+			f.t.Fatalf("timed out waiting for %s", typ)
+		}
+	}
+}
+
+func (f *framework) offer() *mesos.Offer {
+	return f.next(sched.Event_OFFERS).GetOffers().GetOffers()[0]
+}
+
+func (f *framework) accept(offer *mesos.Offer, ops ...*mesos.Offer_Operation) {
+	f.send(&sched.Call{
+		Type: sched.Call_ACCEPT.Enum(),
+		Accept: &sched.Call_Accept{
+			OfferIds:   []*mesos.OfferID{offer.Id},
+			Operations: ops,
+		},
+	}).Body.Close()
+}
+
+func scalar(name string, value float64, role string) *mesos.Resource {
+	res := &mesos.Resource{
+		Name:   proto.String(name),
+		Type:   mesos.Value_SCALAR.Enum(),
+		Scalar: &mesos.Value_Scalar{Value: proto.Float64(value)},
+		Role:   proto.String(role),
+	}
+	if role != "*" {
+		res.Reservation = &mesos.Resource_ReservationInfo{Principal: proto.String("p")}
+	}
+	return res
+}
+
+func volume(id string, size float64) *mesos.Resource {
+	vol := scalar("disk", size, "r")
+	vol.Disk = &mesos.Resource_DiskInfo{
+		Persistence: &mesos.Resource_DiskInfo_Persistence{Id: proto.String(id)},
+		Volume: &mesos.Volume{
+			ContainerPath: proto.String("data"),
+			Mode:          mesos.Volume_RW.Enum(),
+		},
+	}
+	return vol
+}
+
+// amounts sums scalar resources by name, role and volume
+func amounts(resources []*mesos.Resource) map[string]float64 {
+	sums := make(map[string]float64)
+	for _, res := range resources {
+		key := res.GetName() + "(" + res.GetRole() + ")"
+		if id := res.GetDisk().GetPersistence().GetId(); id != "" {
+			key += "[" + id + "]"
+		}
+		sums[key] += res.GetScalar().GetValue()
+	}
+	return sums
+}
+
+func checkAmounts(t *testing.T, what string, resources []*mesos.Resource, want map[string]float64) {
+	t.Helper()
+	got := amounts(resources)
+	if len(got) != len(want) {
+		t.Fatalf("%s %v, want %v", what, got, want)
+	}
+	for key, v := range want {
+		if got[key] != v {
+			t.Fatalf("%s %v, want %v", what, got, want)
+		}
+	}
+}
+
+func TestReserveAndVolumes(t *testing.T) {
+	c := startCluster(t)
+	f := subscribe(t, c)
+
+	f.accept(f.offer(),
+		&mesos.Offer_Operation{
+			Type:    mesos.Offer_Operation_RESERVE.Enum(),
+			Reserve: &mesos.Offer_Operation_Reserve{Resources: []*mesos.Resource{scalar("cpus", 1, "r"), scalar("disk", 60, "r")}},
+		},
+		&mesos.Offer_Operation{
+			Type:   mesos.Offer_Operation_CREATE.Enum(),
+			Create: &mesos.Offer_Operation_Create{Volumes: []*mesos.Resource{volume("v1", 40)}},
+		},
+	)
+	reserved := map[string]float64{
+		"cpus(*)": 1, "cpus(r)": 1, "mem(*)": 256,
+		"disk(*)": 40, "disk(r)": 20, "disk(r)[v1]": 40,
+	}
+	checkAmounts(t, "resources", c.Resources("a1"), reserved)
+	// reservations and volumes are offered again
+	offer := f.offer()
+	checkAmounts(t, "offer", offer.Resources, reserved)
+
+	f.accept(offer,
+		&mesos.Offer_Operation{
+			Type:    mesos.Offer_Operation_DESTROY.Enum(),
+			Destroy: &mesos.Offer_Operation_Destroy{Volumes: []*mesos.Resource{volume("v1", 40)}},
+		},
+		&mesos.Offer_Operation{
+			Type:      mesos.Offer_Operation_UNRESERVE.Enum(),
+			Unreserve: &mesos.Offer_Operation_Unreserve{Resources: []*mesos.Resource{scalar("cpus", 1, "r"), scalar("disk", 60, "r")}},
+		},
+	)
+	checkAmounts(t, "resources", c.Resources("a1"), map[string]float64{
+		"cpus(*)": 2, "mem(*)": 256, "disk(*)": 100,
+	})
+}
+
+func TestInvalidOperations(t *testing.T) {
+	c := startCluster(t)
+	f := subscribe(t, c)
+
+	// more than offered is dropped, the operations after it applied
+	f.accept(f.offer(),
+		&mesos.Offer_Operation{
+			Type:    mesos.Offer_Operation_RESERVE.Enum(),
+			Reserve: &mesos.Offer_Operation_Reserve{Resources: []*mesos.Resource{scalar("disk", 200, "r")}},
+		},
+		&mesos.Offer_Operation{
+			Type:   mesos.Offer_Operation_CREATE.Enum(),
+			Create: &mesos.Offer_Operation_Create{Volumes: []*mesos.Resource{volume("v1", 40)}},
+		},
+		&mesos.Offer_Operation{
+			Type:    mesos.Offer_Operation_RESERVE.Enum(),
+			Reserve: &mesos.Offer_Operation_Reserve{Resources: []*mesos.Resource{scalar("disk", 50, "r")}},
+		},
+	)
+	checkAmounts(t, "resources", c.Resources("a1"), map[string]float64{
+		"cpus(*)": 2, "mem(*)": 256, "disk(*)": 50, "disk(r)": 50,
+	})
+
+	// tasks of an invalid launch fail with TASK_ERROR
+	task := &mesos.TaskInfo{
+		Name:      proto.String("big"),
+		TaskId:    &mesos.TaskID{Value: proto.String("big")},
+		AgentId:   &mesos.AgentID{Value: proto.String("a1")},
+		Resources: []*mesos.Resource{scalar("cpus", 4, "*")},
+	}
+	f.accept(f.offer(), &mesos.Offer_Operation{
+		Type:   mesos.Offer_Operation_LAUNCH.Enum(),
+		Launch: &mesos.Offer_Operation_Launch{TaskInfos: []*mesos.TaskInfo{task}},
+	})
+	status := f.next(sched.Event_UPDATE).GetUpdate().GetStatus()
+	if status.GetState() != mesos.TaskState_TASK_ERROR || status.GetReason() != mesos.TaskStatus_REASON_TASK_INVALID {
+		t.Fatalf("invalid launch reported %s (%s)", status.GetState(), status.GetReason())
+	}
+	if _, ok := c.TaskState("big"); ok {
+		t.Fatal("invalid task tracked")
+	}
+}
+
+func TestLaunchOnVolume(t *testing.T) {
+	c := startCluster(t)
+	f := subscribe(t, c)
+
+	task := &mesos.TaskInfo{
+		Name:      proto.String("t1"),
+		TaskId:    &mesos.TaskID{Value: proto.String("t1")},
+		AgentId:   &mesos.AgentID{Value: proto.String("a1")},
+		Resources: []*mesos.Resource{scalar("cpus", 1, "*"), volume("v1", 40)},
+	}
+	f.accept(f.offer(),
+		&mesos.Offer_Operation{
+			Type:    mesos.Offer_Operation_RESERVE.Enum(),
+			Reserve: &mesos.Offer_Operation_Reserve{Resources: []*mesos.Resource{scalar("disk", 40, "r")}},
+		},
+		&mesos.Offer_Operation{
+			Type:   mesos.Offer_Operation_CREATE.Enum(),
+			Create: &mesos.Offer_Operation_Create{Volumes: []*mesos.Resource{volume("v1", 40)}},
+		},
+		&mesos.Offer_Operation{
+			Type:   mesos.Offer_Operation_LAUNCH.Enum(),
+			Launch: &mesos.Offer_Operation_Launch{TaskInfos: []*mesos.TaskInfo{task}},
+		},
+	)
+	// the task holds the volume while running
+	checkAmounts(t, "resources", c.Resources("a1"), map[string]float64{
+		"cpus(*)": 1, "mem(*)": 256, "disk(*)": 60,
+	})
+
+	for _, want := range []mesos.TaskState{mesos.TaskState_TASK_RUNNING, mesos.TaskState_TASK_FINISHED} {
+		if got := f.next(sched.Event_UPDATE).GetUpdate().GetStatus().GetState(); got != want {
+			t.Fatalf("task %s, want %s", got, want)
+		}
+	}
+	// the volume outlives the task
+	checkAmounts(t, "resources", c.Resources("a1"), map[string]float64{
+		"cpus(*)": 2, "mem(*)": 256, "disk(*)": 60, "disk(r)[v1]": 40,
+	})
+
+	// its persistence ID stays in use
+	f.accept(f.offer(),
+		&mesos.Offer_Operation{
+			Type:    mesos.Offer_Operation_RESERVE.Enum(),
+			Reserve: &mesos.Offer_Operation_Reserve{Resources: []*mesos.Resource{scalar("disk", 40, "r")}},
+		},
+		&mesos.Offer_Operation{
+			Type:   mesos.Offer_Operation_CREATE.Enum(),
+			Create: &mesos.Offer_Operation_Create{Volumes: []*mesos.Resource{volume("v1", 40)}},
+		},
+	)
+	checkAmounts(t, "resources", c.Resources("a1"), map[string]float64{
+		"cpus(*)": 2, "mem(*)": 256, "disk(*)": 20, "disk(r)": 40, "disk(r)[v1]": 40,
+	})
+}
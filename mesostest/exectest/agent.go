@@ -0,0 +1,442 @@
+package exectest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os/user"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	exec "github.com/vladimirvivien/mesos-http/mesos/exec"
+	"github.com/vladimirvivien/mesos-http/mesos/mesos"
+	"github.com/vladimirvivien/mesos-http/mesostest/internal/stream"
+	"github.com/vladimirvivien/mesos-http/uuid"
+)
+
+// ExecutorPath is the executor API endpoint served by Agent
+const ExecutorPath = "/api/v1/executor"
+
+// Agent is an in-process fake Mesos agent serving the executor
+// HTTP API for a single executor. It answers SUBSCRIBE with a
+// RecordIO event stream starting with SUBSCRIBED, lets tests push
+// LAUNCH, KILL, MESSAGE and SHUTDOWN events, and verifies the
+// UPDATE calls the executor sends back.
+type Agent struct {
+	// AutoAck makes the agent acknowledge every valid update
+	// right away, as an agent does once the scheduler acks.
+	AutoAck bool
+
+	FrameworkID *mesos.FrameworkID
+	ExecutorID  *mesos.ExecutorID
+	AgentID     *mesos.AgentID
+
+	server *httptest.Server
+
+	mu            sync.Mutex
+	calls         []*exec.Call
+	changed       chan struct{}
+	errors        []error
+	stream        *stream.Stream
+	streams       int
+	down          bool
+	tasks         map[string]*mesos.TaskInfo
+	inflight      map[string]uuid.UUID
+	subscriptions []*exec.Call_Subscribe
+	updates       []*mesos.TaskStatus
+	rejected      map[exec.Call_Type]int
+}
+
+// NewAgent starts a fake agent on a local port for the executor
+// with the given framework and executor IDs.
+func NewAgent(frameworkID, executorID string) *Agent {
+	a := &Agent{
+		AutoAck:     true,
+		FrameworkID: &mesos.FrameworkID{Value: proto.String(frameworkID)},
+		ExecutorID:  &mesos.ExecutorID{Value: proto.String(executorID)},
+		AgentID:     &mesos.AgentID{Value: proto.String("agent-1")},
+		changed:     make(chan struct{}),
+		tasks:       make(map[string]*mesos.TaskInfo),
+		inflight:    make(map[string]uuid.UUID),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(ExecutorPath, a.handle)
+	a.server = httptest.NewServer(mux)
+	return a
+}
+
+// Addr returns the agent address as <ip:port>
+func (a *Agent) Addr() string {
+	return strings.TrimPrefix(a.server.URL, "http://")
+}
+
+// Env returns the environment an agent passes to the executor
+func (a *Agent) Env() []string {
+	return []string{
+		"MESOS_AGENT_ENDPOINT=" + a.Addr(),
+		"MESOS_FRAMEWORK_ID=" + a.FrameworkID.GetValue(),
+		"MESOS_EXECUTOR_ID=" + a.ExecutorID.GetValue(),
+	}
+}
+
+// Close disconnects the executor and stops the server
+func (a *Agent) Close() {
+	a.disconnect()
+	a.server.Close()
+}
+
+// Launch sends a LAUNCH event for the task
+func (a *Agent) Launch(task *mesos.TaskInfo) error {
+	a.mu.Lock()
+	a.tasks[task.GetTaskId().GetValue()] = task
+	a.mu.Unlock()
+	return a.Send(&exec.Event{
+		Type:   exec.Event_LAUNCH.Enum(),
+		Launch: &exec.Event_Launch{Task: task},
+	})
+}
+
+// Kill sends a KILL event for the task
+func (a *Agent) Kill(taskID string) error {
+	return a.Send(&exec.Event{
+		Type: exec.Event_KILL.Enum(),
+		Kill: &exec.Event_Kill{TaskId: &mesos.TaskID{Value: proto.String(taskID)}},
+	})
+}
+
+// Ack sends an ACKNOWLEDGED event for an update
+func (a *Agent) Ack(taskID *mesos.TaskID, id []byte) error {
+	a.mu.Lock()
+	if inflight, ok := a.inflight[taskID.GetValue()]; ok && string(inflight[:]) == string(id) {
+		delete(a.inflight, taskID.GetValue())
+	}
+	a.mu.Unlock()
+	return a.Send(&exec.Event{
+		Type: exec.Event_ACKNOWLEDGED.Enum(),
+		Acknowledged: &exec.Event_Acknowledged{
+			TaskId: taskID,
+			Uuid:   id,
+		},
+	})
+}
+
+// Message sends a framework MESSAGE event
+func (a *Agent) Message(data []byte) error {
+	return a.Send(&exec.Event{
+		Type:    exec.Event_MESSAGE.Enum(),
+		Message: &exec.Event_Message{Data: data},
+	})
+}
+
+// Shutdown sends a SHUTDOWN event
+func (a *Agent) Shutdown() error {
+	return a.Send(&exec.Event{Type: exec.Event_SHUTDOWN.Enum()})
+}
+
+// Send emits an event on the executor's subscription stream
+func (a *Agent) Send(event *exec.Event) error {
+	a.mu.Lock()
+	st := a.stream
+	a.mu.Unlock()
+	if st == nil {
+		return fmt.Errorf("exectest: no subscribed executor")
+	}
+	return st.Send(event)
+}
+
+// Restart simulates an agent restart: the stream is closed and all
+// requests fail for the given downtime, after which the executor
+// may resubscribe. Launched tasks are remembered.
+func (a *Agent) Restart(downtime time.Duration) {
+	a.mu.Lock()
+	a.down = true
+	a.mu.Unlock()
+	a.disconnect()
+
+	time.AfterFunc(downtime, func() {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		a.down = false
+	})
+}
+
+// Reject makes the agent answer the calls of type t with the given
+// HTTP status instead of accepting them, or accept them again if
+// status is 0. Rejected calls are recorded, but not verified.
+func (a *Agent) Reject(t exec.Call_Type, status int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.rejected == nil {
+		a.rejected = make(map[exec.Call_Type]int)
+	}
+	if status == 0 {
+		delete(a.rejected, t)
+		return
+	}
+	a.rejected[t] = status
+}
+
+func (a *Agent) disconnect() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.stream != nil {
+		a.stream.Close()
+		a.stream = nil
+	}
+}
+
+// Calls returns every call received so far
+func (a *Agent) Calls() []*exec.Call {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]*exec.Call(nil), a.calls...)
+}
+
+// Updates returns the statuses of the valid UPDATE calls received
+func (a *Agent) Updates() []*mesos.TaskStatus {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]*mesos.TaskStatus(nil), a.updates...)
+}
+
+// Subscriptions returns the SUBSCRIBE calls received, including
+// the unacknowledged tasks and updates sent on resubscription.
+func (a *Agent) Subscriptions() []*exec.Call_Subscribe {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]*exec.Call_Subscribe(nil), a.subscriptions...)
+}
+
+// Errors returns the verification failures of received calls
+func (a *Agent) Errors() []error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]error(nil), a.errors...)
+}
+
+// WaitForUpdate waits for an update of the task to the given state
+func (a *Agent) WaitForUpdate(taskID string, state mesos.TaskState, timeout time.Duration) (*mesos.TaskStatus, error) {
+	deadline := time.After(timeout)
+	for {
+		a.mu.Lock()
+		changed := a.changed
+		a.mu.Unlock()
+
+		for _, status := range a.Updates() {
+			if status.GetTaskId().GetValue() == taskID && status.GetState() == state {
+				return status, nil
+			}
+		}
+		select {
+		case <-changed:
+		case <-deadline:
+			return nil, fmt.Errorf("exectest: timed out waiting for task %s to be %s", taskID, state)
+		}
+	}
+}
+
+// WaitForSubscribe waits until n SUBSCRIBE calls were received
+func (a *Agent) WaitForSubscribe(n int, timeout time.Duration) error {
+	deadline := time.After(timeout)
+	for {
+		a.mu.Lock()
+		changed := a.changed
+		count := len(a.subscriptions)
+		a.mu.Unlock()
+
+		if count >= n {
+			return nil
+		}
+		select {
+		case <-changed:
+		case <-deadline:
+			return fmt.Errorf("exectest: timed out waiting for %d subscriptions", n)
+		}
+	}
+}
+
+func (a *Agent) record(call *exec.Call, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.calls = append(a.calls, call)
+	if err != nil {
+		a.errors = append(a.errors, err)
+	}
+	if call.GetType() == exec.Call_SUBSCRIBE {
+		a.subscriptions = append(a.subscriptions, call.GetSubscribe())
+	}
+	if call.GetType() == exec.Call_UPDATE && err == nil {
+		status := call.GetUpdate().GetStatus()
+		a.updates = append(a.updates, status)
+		a.inflight[status.GetTaskId().GetValue()], _ = uuid.FromBytes(status.GetUuid())
+	}
+	close(a.changed)
+	a.changed = make(chan struct{})
+}
+
+// validate checks a call against the executor API rules
+func (a *Agent) validate(call *exec.Call) error {
+	if call.GetFrameworkId().GetValue() != a.FrameworkID.GetValue() {
+		return fmt.Errorf("unexpected framework ID %q", call.GetFrameworkId().GetValue())
+	}
+	if call.GetExecutorId().GetValue() != a.ExecutorID.GetValue() {
+		return fmt.Errorf("unexpected executor ID %q", call.GetExecutorId().GetValue())
+	}
+
+	switch call.GetType() {
+	case exec.Call_SUBSCRIBE:
+		if call.Subscribe == nil {
+			return fmt.Errorf("SUBSCRIBE without subscribe")
+		}
+	case exec.Call_UPDATE:
+		status := call.GetUpdate().GetStatus()
+		if status == nil {
+			return fmt.Errorf("UPDATE without status")
+		}
+		id, err := uuid.FromBytes(status.GetUuid())
+		if err != nil || id.Version() != 4 {
+			return fmt.Errorf("UPDATE of task %s has invalid UUID", status.GetTaskId().GetValue())
+		}
+		if status.GetSource() != mesos.TaskStatus_SOURCE_EXECUTOR {
+			return fmt.Errorf("UPDATE of task %s has source %s", status.GetTaskId().GetValue(), status.GetSource())
+		}
+		if status.GetState() == mesos.TaskState_TASK_STAGING {
+			return fmt.Errorf("UPDATE of task %s to TASK_STAGING", status.GetTaskId().GetValue())
+		}
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		taskID := status.GetTaskId().GetValue()
+		if _, known := a.tasks[taskID]; !known {
+			return fmt.Errorf("UPDATE of unknown task %s", taskID)
+		}
+		// resending the update in flight is fine, a new one is not
+		if inflight, ok := a.inflight[taskID]; ok && inflight != id {
+			return fmt.Errorf("UPDATE of task %s while %s is not acknowledged", taskID, inflight)
+		}
+	case exec.Call_MESSAGE:
+		if call.Message == nil {
+			return fmt.Errorf("MESSAGE without message")
+		}
+	default:
+		return fmt.Errorf("unknown call type %s", call.GetType())
+	}
+	return nil
+}
+
+func (a *Agent) handle(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	down := a.down
+	a.mu.Unlock()
+	if down {
+		http.Error(w, "Agent is recovering", http.StatusServiceUnavailable)
+		return
+	}
+	if r.Method != "POST" {
+		http.Error(w, "Expecting POST", http.StatusMethodNotAllowed)
+		return
+	}
+
+	call := new(exec.Call)
+	data, err := ioutil.ReadAll(r.Body)
+	if err == nil {
+		if r.Header.Get("Content-Type") == "application/json" {
+			err = json.Unmarshal(data, call)
+		} else {
+			err = proto.Unmarshal(data, call)
+		}
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	a.mu.Lock()
+	status := a.rejected[call.GetType()]
+	if status != 0 {
+		a.calls = append(a.calls, call)
+	}
+	a.mu.Unlock()
+	if status != 0 {
+		http.Error(w, fmt.Sprintf("%s rejected", call.GetType()), status)
+		return
+	}
+	err = a.validate(call)
+	a.record(call, err)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch call.GetType() {
+	case exec.Call_SUBSCRIBE:
+		a.subscribe(w, r, call)
+	case exec.Call_UPDATE:
+		w.WriteHeader(http.StatusAccepted)
+		if a.AutoAck {
+			status := call.GetUpdate().GetStatus()
+			go a.Ack(status.GetTaskId(), status.GetUuid())
+		}
+	default:
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+// subscribe opens a new stream, replacing the previous one
+func (a *Agent) subscribe(w http.ResponseWriter, r *http.Request, call *exec.Call) {
+	for _, task := range call.GetSubscribe().GetUnacknowledgedTasks() {
+		a.mu.Lock()
+		a.tasks[task.GetTaskId().GetValue()] = task
+		a.mu.Unlock()
+	}
+
+	a.mu.Lock()
+	if a.stream != nil {
+		a.stream.Close()
+	}
+	a.streams++
+	st := stream.New(fmt.Sprintf("executor-stream-%d", a.streams))
+	a.stream = st
+	a.mu.Unlock()
+
+	err := st.Send(&exec.Event{
+		Type: exec.Event_SUBSCRIBED.Enum(),
+		Subscribed: &exec.Event_Subscribed{
+			ExecutorInfo: &mesos.ExecutorInfo{
+				ExecutorId: a.ExecutorID,
+				Command:    &mesos.CommandInfo{Value: proto.String("executor")},
+			},
+			FrameworkInfo: &mesos.FrameworkInfo{
+				Id:   a.FrameworkID,
+				User: proto.String(currentUser()),
+				Name: proto.String("mesostest"),
+			},
+			AgentInfo: &mesos.AgentInfo{
+				Id:       a.AgentID,
+				Hostname: proto.String("localhost"),
+			},
+		},
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+	st.Serve(w, r)
+}
+
+// currentUser returns the user running the agent, which the
+// executor runs tasks as by default.
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return ""
+}
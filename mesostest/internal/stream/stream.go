@@ -0,0 +1,75 @@
+package stream
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/vladimirvivien/mesos-http/recordio"
+)
+
+// Stream is an open RecordIO event stream to a subscriber
+type Stream struct {
+	ID      string
+	records chan []byte
+	done    chan struct{}
+}
+
+// New returns a stream with the given ID
+func New(id string) *Stream {
+	return &Stream{
+		ID:      id,
+		records: make(chan []byte, 1024),
+		done:    make(chan struct{}),
+	}
+}
+
+// Serve writes queued records until the stream or request ends
+func (s *Stream) Serve(w http.ResponseWriter, r *http.Request) {
+	flusher, _ := w.(http.Flusher)
+	for {
+		select {
+		case data := <-s.records:
+			if err := recordio.Write(w, data); err != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		case <-s.done:
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// Send queues an event. Events are encoded with encoding/json,
+// the way the schedulers and executor of this repository decode
+// them.
+func (s *Stream) Send(event interface{}) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	select {
+	case s.records <- data:
+		return nil
+	case <-s.done:
+		return fmt.Errorf("mesostest: stream %s closed", s.ID)
+	}
+}
+
+// Done returns a channel closed with the stream
+func (s *Stream) Done() <-chan struct{} {
+	return s.done
+}
+
+// Close ends the stream
+func (s *Stream) Close() {
+	select {
+	case <-s.done:
+	default:
+		close(s.done)
+	}
+}
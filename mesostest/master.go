@@ -0,0 +1,368 @@
+package mesostest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/vladimirvivien/mesos-http/mesos/mesos"
+	"github.com/vladimirvivien/mesos-http/mesos/sched"
+	"github.com/vladimirvivien/mesos-http/mesostest/internal/stream"
+	"github.com/vladimirvivien/mesos-http/uuid"
+)
+
+// SchedulerPath is the scheduler API endpoint served by Master
+const SchedulerPath = "/api/v1/scheduler"
+
+// Master is an in-process fake Mesos master serving the scheduler
+// HTTP API. It answers SUBSCRIBE with a RecordIO event stream,
+// starting with SUBSCRIBED followed by the scripted events, and
+// records every call it receives.
+type Master struct {
+	// Heartbeat, when set before subscription, makes the master
+	// emit HEARTBEAT events at that interval.
+	Heartbeat time.Duration
+
+	// OnCall, when set before use, is called with every accepted
+	// call; for SUBSCRIBE it is called once the stream is open.
+	// It lets simulations react to calls, e.g. launch tasks.
+	OnCall func(call *sched.Call)
+
+	server *httptest.Server
+
+	mu       sync.Mutex
+	calls    []*sched.Call
+	changed  chan struct{}
+	script   []*sched.Event
+	stream   *stream.Stream
+	streams  int
+	fwCount  int
+	lastFwID *mesos.FrameworkID
+	rejected map[sched.Call_Type]int
+}
+
+// NewMaster starts a fake master on a local port
+func NewMaster() *Master {
+	m := &Master{changed: make(chan struct{})}
+	m.server = httptest.NewServer(m.mux())
+	return m
+}
+
+// NewMasterAt starts a fake master listening on addr, e.g.
+// "127.0.0.1:5050", so it can serve scheduler binaries.
+func NewMasterAt(addr string) (*Master, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	m := &Master{changed: make(chan struct{})}
+	m.server = httptest.NewUnstartedServer(m.mux())
+	m.server.Listener.Close()
+	m.server.Listener = l
+	m.server.Start()
+	return m, nil
+}
+
+func (m *Master) mux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc(SchedulerPath, m.handle)
+	return mux
+}
+
+// Addr returns the master address as <ip:port>
+func (m *Master) Addr() string {
+	return strings.TrimPrefix(m.server.URL, "http://")
+}
+
+// Close disconnects the subscriber and stops the server
+func (m *Master) Close() {
+	m.Disconnect()
+	m.server.Close()
+}
+
+// Script queues events sent right after SUBSCRIBED on the
+// next subscription.
+func (m *Master) Script(events ...*sched.Event) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.script = append(m.script, events...)
+}
+
+// Send emits an event on the current subscription stream
+func (m *Master) Send(event *sched.Event) error {
+	m.mu.Lock()
+	st := m.stream
+	m.mu.Unlock()
+	if st == nil {
+		return fmt.Errorf("mesostest: no subscribed scheduler")
+	}
+	return st.Send(event)
+}
+
+// Disconnect closes the current subscription stream, as a master
+// failover would.
+func (m *Master) Disconnect() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stream != nil {
+		m.stream.Close()
+		m.stream = nil
+	}
+}
+
+// StreamID returns the ID of the current subscription stream
+func (m *Master) StreamID() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stream == nil {
+		return ""
+	}
+	return m.stream.ID
+}
+
+// FrameworkID returns the ID assigned on the last subscription
+func (m *Master) FrameworkID() *mesos.FrameworkID {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.lastFwID
+}
+
+// Calls returns every call received so far
+func (m *Master) Calls() []*sched.Call {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*sched.Call(nil), m.calls...)
+}
+
+// CallsOf returns the received calls of the given type
+func (m *Master) CallsOf(t sched.Call_Type) []*sched.Call {
+	var calls []*sched.Call
+	for _, c := range m.Calls() {
+		if c.GetType() == t {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+// WaitForCall waits until n calls of the given type were received
+// and returns them.
+func (m *Master) WaitForCall(t sched.Call_Type, n int, timeout time.Duration) ([]*sched.Call, error) {
+	deadline := time.After(timeout)
+	for {
+		m.mu.Lock()
+		changed := m.changed
+		m.mu.Unlock()
+
+		if calls := m.CallsOf(t); len(calls) >= n {
+			return calls, nil
+		}
+		select {
+		case <-changed:
+		case <-deadline:
+			return nil, fmt.Errorf("mesostest: timed out waiting for %d %s calls", n, t)
+		}
+	}
+}
+
+// Reject makes the master answer the calls of type t with the given
+// HTTP status instead of accepting them, or accept them again if
+// status is 0. Rejected calls are recorded all the same.
+func (m *Master) Reject(t sched.Call_Type, status int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.rejected == nil {
+		m.rejected = make(map[sched.Call_Type]int)
+	}
+	if status == 0 {
+		delete(m.rejected, t)
+		return
+	}
+	m.rejected[t] = status
+}
+
+func (m *Master) record(call *sched.Call) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls = append(m.calls, call)
+	close(m.changed)
+	m.changed = make(chan struct{})
+}
+
+func (m *Master) handle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Expecting POST", http.StatusMethodNotAllowed)
+		return
+	}
+	call := new(sched.Call)
+	if err := decodeCall(r, call); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	m.record(call)
+
+	if call.GetType() == sched.Call_SUBSCRIBE {
+		m.subscribe(w, r, call)
+		return
+	}
+	if id := r.Header.Get("Mesos-Stream-Id"); id == "" || id != m.StreamID() {
+		http.Error(w, "Invalid Mesos-Stream-Id", http.StatusBadRequest)
+		return
+	}
+	m.mu.Lock()
+	status := m.rejected[call.GetType()]
+	m.mu.Unlock()
+	if status != 0 {
+		http.Error(w, fmt.Sprintf("%s rejected", call.GetType()), status)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+	if m.OnCall != nil {
+		m.OnCall(call)
+	}
+}
+
+// subscribe opens a new stream, replacing the previous one
+func (m *Master) subscribe(w http.ResponseWriter, r *http.Request, call *sched.Call) {
+	m.mu.Lock()
+	if m.stream != nil {
+		m.stream.Close()
+	}
+	m.streams++
+	st := stream.New(fmt.Sprintf("stream-%d", m.streams))
+	m.stream = st
+
+	fwID := call.GetSubscribe().GetFrameworkInfo().GetId()
+	if fwID.GetValue() == "" {
+		m.fwCount++
+		fwID = &mesos.FrameworkID{Value: proto.String(fmt.Sprintf("framework-%d", m.fwCount))}
+	}
+	m.lastFwID = fwID
+	script := m.script
+	m.script = nil
+	heartbeat := m.Heartbeat
+	m.mu.Unlock()
+
+	subscribed := SubscribedEvent(fwID.GetValue())
+	if heartbeat > 0 {
+		subscribed.Subscribed.HeartbeatIntervalSeconds = proto.Float64(heartbeat.Seconds())
+		go m.heartbeats(st, heartbeat)
+	}
+	for _, ev := range append([]*sched.Event{subscribed}, script...) {
+		if err := st.Send(ev); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if m.OnCall != nil {
+		m.OnCall(call)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Mesos-Stream-Id", st.ID)
+	w.WriteHeader(http.StatusOK)
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+	st.Serve(w, r)
+}
+
+func (m *Master) heartbeats(st *stream.Stream, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if st.Send(HeartbeatEvent()) != nil {
+				return
+			}
+		case <-st.Done():
+			return
+		}
+	}
+}
+
+// decodeCall reads a protobuf or JSON encoded call
+func decodeCall(r *http.Request, call *sched.Call) error {
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	if r.Header.Get("Content-Type") == "application/json" {
+		return json.Unmarshal(data, call)
+	}
+	return proto.Unmarshal(data, call)
+}
+
+// SubscribedEvent returns a SUBSCRIBED event
+func SubscribedEvent(frameworkID string) *sched.Event {
+	return &sched.Event{
+		Type: sched.Event_SUBSCRIBED.Enum(),
+		Subscribed: &sched.Event_Subscribed{
+			FrameworkId: &mesos.FrameworkID{Value: proto.String(frameworkID)},
+		},
+	}
+}
+
+// OffersEvent returns an OFFERS event
+func OffersEvent(offers ...*mesos.Offer) *sched.Event {
+	return &sched.Event{
+		Type:   sched.Event_OFFERS.Enum(),
+		Offers: &sched.Event_Offers{Offers: offers},
+	}
+}
+
+// UpdateEvent returns an UPDATE event
+func UpdateEvent(status *mesos.TaskStatus) *sched.Event {
+	return &sched.Event{
+		Type:   sched.Event_UPDATE.Enum(),
+		Update: &sched.Event_Update{Status: status},
+	}
+}
+
+// HeartbeatEvent returns a HEARTBEAT event
+func HeartbeatEvent() *sched.Event {
+	return &sched.Event{Type: sched.Event_HEARTBEAT.Enum()}
+}
+
+// Offer returns an offer of cpus and mem on the given agent
+func Offer(id, agentID, hostname string, cpus, mem float64) *mesos.Offer {
+	return &mesos.Offer{
+		Id:          &mesos.OfferID{Value: proto.String(id)},
+		FrameworkId: &mesos.FrameworkID{Value: proto.String("")},
+		AgentId:     &mesos.AgentID{Value: proto.String(agentID)},
+		Hostname:    proto.String(hostname),
+		Resources: []*mesos.Resource{
+			Scalar("cpus", cpus),
+			Scalar("mem", mem),
+		},
+	}
+}
+
+// Scalar returns a scalar resource
+func Scalar(name string, value float64) *mesos.Resource {
+	return &mesos.Resource{
+		Name:   proto.String(name),
+		Type:   mesos.Value_SCALAR.Enum(),
+		Scalar: &mesos.Value_Scalar{Value: proto.Float64(value)},
+	}
+}
+
+// Status returns a task status update with a fresh UUID
+func Status(taskID, agentID string, state mesos.TaskState) *mesos.TaskStatus {
+	return &mesos.TaskStatus{
+		TaskId:  &mesos.TaskID{Value: proto.String(taskID)},
+		AgentId: &mesos.AgentID{Value: proto.String(agentID)},
+		State:   state.Enum(),
+		Source:  mesos.TaskStatus_SOURCE_EXECUTOR.Enum(),
+		Uuid:    uuid.New().Bytes(),
+	}
+}
@@ -0,0 +1,76 @@
+package message
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/gogo/protobuf/proto"
+)
+
+// Codec encodes message payloads. The codec name travels with each
+// message so that the receiver decodes it the same way.
+type Codec interface {
+	Name() string
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// JSON encodes payloads with encoding/json
+var JSON Codec = jsonCodec{}
+
+// Proto encodes payloads that are protobuf messages
+var Proto Codec = protoCodec{}
+
+// codecsMu guards codecs, as codecs may be registered while
+// endpoints receive.
+var codecsMu sync.RWMutex
+
+var codecs = map[string]Codec{
+	JSON.Name():  JSON,
+	Proto.Name(): Proto,
+}
+
+// Register makes a codec available to receivers. It is safe to call
+// concurrently with receivers.
+func Register(c Codec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	codecs[c.Name()] = c
+}
+
+func codecOf(name string) (Codec, error) {
+	codecsMu.RLock()
+	c, ok := codecs[name]
+	codecsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("message: unknown codec %q", name)
+	}
+	return c, nil
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string                               { return "json" }
+func (jsonCodec) Marshal(v interface{}) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+type protoCodec struct{}
+
+func (protoCodec) Name() string { return "proto" }
+
+func (protoCodec) Marshal(v interface{}) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("message: %T is not a protobuf message", v)
+	}
+	return proto.Marshal(m)
+}
+
+func (protoCodec) Unmarshal(data []byte, v interface{}) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("message: %T is not a protobuf message", v)
+	}
+	return proto.Unmarshal(data, m)
+}
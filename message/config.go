@@ -0,0 +1,16 @@
+package message
+
+// ConfigReloadType is the message type of configuration reloads
+// pushed by a scheduler to its running executors.
+const ConfigReloadType = "config.reload"
+
+// ConfigReload carries executor settings, named after the
+// environment variables that configure them at launch.
+type ConfigReload struct {
+	Settings map[string]string `json:"settings"`
+}
+
+// ConfigReloaded reports the settings an executor applied
+type ConfigReloaded struct {
+	Applied []string `json:"applied"`
+}
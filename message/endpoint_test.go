@@ -0,0 +1,197 @@
+package message
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const timeout = time.Second
+
+var executor = Address{AgentID: "a1", ExecutorID: "e1"}
+
+// pair returns a scheduler and an executor endpoint sending to each
+// other. Messages are dropped while drop is set.
+func pair(codec Codec) (sched, exec *Endpoint, drop *bool) {
+	drop = new(bool)
+	sched = NewEndpoint(func(to Address, data []byte) error {
+		if *drop {
+			return nil
+		}
+		return exec.Receive(Address{}, data)
+	}, codec)
+	exec = NewEndpoint(func(to Address, data []byte) error {
+		if *drop {
+			return nil
+		}
+		return sched.Receive(executor, data)
+	}, codec)
+	return sched, exec, drop
+}
+
+type echo struct {
+	Text  string `json:"text"`
+	Delay time.Duration
+}
+
+func echoHandler(msg *Message) (interface{}, error) {
+	req := new(echo)
+	if err := msg.Decode(req); err != nil {
+		return nil, err
+	}
+	if req.Text == "" {
+		return nil, fmt.Errorf("empty echo")
+	}
+	time.Sleep(req.Delay)
+	return &echo{Text: strings.ToUpper(req.Text)}, nil
+}
+
+func TestRequestReply(t *testing.T) {
+	sched, exec, _ := pair(JSON)
+	exec.Handle("echo", echoHandler)
+
+	reply := new(echo)
+	if err := sched.Request(executor, "echo", &echo{Text: "hi"}, reply, timeout); err != nil {
+		t.Fatal(err)
+	}
+	if reply.Text != "HI" {
+		t.Errorf("reply %q, want HI", reply.Text)
+	}
+
+	// handler errors are the errors of the request
+	err := sched.Request(executor, "echo", &echo{}, reply, timeout)
+	if err == nil || err.Error() != "empty echo" {
+		t.Errorf("request error %v, want empty echo", err)
+	}
+	err = sched.Request(executor, "unknown", &echo{Text: "hi"}, reply, timeout)
+	if err == nil || !strings.Contains(err.Error(), "no handler") {
+		t.Errorf("request error %v, want no handler", err)
+	}
+}
+
+func TestRepliesMatchRequests(t *testing.T) {
+	sched, exec, _ := pair(JSON)
+	exec.Handle("echo", echoHandler)
+
+	// the first requests are answered last
+	words := []string{"one", "two", "three", "four", "five"}
+	var wg sync.WaitGroup
+	for i, word := range words {
+		wg.Add(1)
+		go func(word string, delay time.Duration) {
+			defer wg.Done()
+			reply := new(echo)
+			if err := sched.Request(executor, "echo", &echo{Text: word, Delay: delay}, reply, timeout); err != nil {
+				t.Error(err)
+				return
+			}
+			if reply.Text != strings.ToUpper(word) {
+				t.Errorf("reply %q to %q", reply.Text, word)
+			}
+		}(word, time.Duration(len(words)-i)*10*time.Millisecond)
+	}
+	wg.Wait()
+}
+
+func TestRequestTimeout(t *testing.T) {
+	sched, exec, drop := pair(JSON)
+	exec.Handle("echo", echoHandler)
+	*drop = true
+	err := sched.Request(executor, "echo", &echo{Text: "hi"}, nil, 50*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "no reply") {
+		t.Fatalf("request error %v, want a timeout", err)
+	}
+
+	// a late reply no longer matches a pending request
+	late, _ := json.Marshal(&envelope{ID: "r1", InReplyTo: sched.prefix + "-1", Type: "echo", Codec: "json"})
+	if err := sched.Receive(executor, late); err == nil {
+		t.Error("late reply accepted")
+	}
+}
+
+func TestReceive(t *testing.T) {
+	sched, _, _ := pair(JSON)
+	received := make(chan string, 1)
+	sched.Handle("event", func(msg *Message) (interface{}, error) {
+		v := new(echo)
+		err := msg.Decode(v)
+		received <- fmt.Sprintf("%s from %s: %s %v", msg.Type, msg.From.ExecutorID, v.Text, err)
+		return nil, nil
+	})
+
+	for _, test := range []struct {
+		name string
+		data string
+	}{
+		{"invalid envelope", "{"},
+		{"unknown codec", `{"id": "1", "type": "event", "codec": "xml"}`},
+		{"unexpected reply", `{"id": "2", "in_reply_to": "x-1", "type": "event", "codec": "json"}`},
+		{"no handler", `{"id": "3", "type": "other", "codec": "json"}`},
+	} {
+		if err := sched.Receive(executor, []byte(test.data)); err == nil {
+			t.Errorf("%s received", test.name)
+		}
+	}
+
+	payload, _ := json.Marshal(&echo{Text: "up"})
+	data, _ := json.Marshal(&envelope{ID: "4", Type: "event", Codec: "json", Payload: payload})
+	if err := sched.Receive(executor, data); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-received:
+		if want := "event from e1: up <nil>"; got != want {
+			t.Errorf("received %q, want %q", got, want)
+		}
+	case <-time.After(timeout):
+		t.Fatal("message not handled")
+	}
+}
+
+// upper is a codec known only once registered
+type upper struct{}
+
+func (upper) Name() string { return "upper" }
+func (upper) Marshal(v interface{}) ([]byte, error) {
+	return []byte(strings.ToUpper(*v.(*string))), nil
+}
+func (upper) Unmarshal(data []byte, v interface{}) error {
+	*v.(*string) = string(data)
+	return nil
+}
+
+func TestRegisterCodec(t *testing.T) {
+	sched, exec, _ := pair(JSON)
+	received := make(chan string, 1)
+	exec.Handle("text", func(msg *Message) (interface{}, error) {
+		var s string
+		msg.Decode(&s)
+		received <- s
+		return nil, nil
+	})
+	text := "hello"
+	if err := sched.SendWith(upper{}, executor, "text", &text); err == nil {
+		t.Fatal("message with an unknown codec received")
+	}
+
+	Register(upper{})
+	t.Cleanup(func() {
+		codecsMu.Lock()
+		delete(codecs, upper{}.Name())
+		codecsMu.Unlock()
+	})
+	if err := sched.SendWith(upper{}, executor, "text", &text); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-received:
+		if got != "HELLO" {
+			t.Errorf("received %q, want HELLO", got)
+		}
+	case <-time.After(timeout):
+		t.Fatal("message not handled")
+	}
+}
@@ -0,0 +1,233 @@
+// Package message implements typed request/response messaging over
+// the framework MESSAGE calls and events exchanged by a scheduler
+// and its executors.
+//
+// Every message is a JSON envelope carrying a type, a correlation
+// ID and a payload encoded with a Codec. Requests are answered by
+// the handler registered for their type on the other side; the
+// reply carries the ID of the request in InReplyTo.
+package message
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Address identifies the executor a scheduler exchanges messages
+// with. Executors send to their scheduler, using a zero Address.
+type Address struct {
+	AgentID    string
+	ExecutorID string
+}
+
+// envelope is the message data sent over MESSAGE calls
+type envelope struct {
+	ID        string `json:"id"`
+	InReplyTo string `json:"in_reply_to,omitempty"`
+	Type      string `json:"type"`
+	Codec     string `json:"codec"`
+	Payload   []byte `json:"payload,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Message is a received message
+type Message struct {
+	From Address
+	Type string
+	ID   string
+
+	env   *envelope
+	codec Codec
+}
+
+// Decode decodes the message payload into v
+func (m *Message) Decode(v interface{}) error {
+	return m.codec.Unmarshal(m.env.Payload, v)
+}
+
+// Handler handles a message of a registered type. For requests, a
+// non-nil result or an error is sent back as the reply.
+type Handler func(msg *Message) (interface{}, error)
+
+// Sender sends message data to an address, typically as a
+// scheduler or executor MESSAGE call.
+type Sender func(to Address, data []byte) error
+
+// Endpoint sends and receives messages on one side of the channel
+type Endpoint struct {
+	send   Sender
+	codec  Codec
+	prefix string
+	seq    uint64
+
+	mu       sync.Mutex
+	handlers map[string]Handler
+	pending  map[string]chan *envelope
+}
+
+// NewEndpoint returns an endpoint sending with send and encoding
+// payloads with codec.
+func NewEndpoint(send Sender, codec Codec) *Endpoint {
+	prefix := make([]byte, 4)
+	rand.Read(prefix)
+	return &Endpoint{
+		send:     send,
+		codec:    codec,
+		prefix:   hex.EncodeToString(prefix),
+		handlers: make(map[string]Handler),
+		pending:  make(map[string]chan *envelope),
+	}
+}
+
+// Handle registers the handler of a message type
+func (e *Endpoint) Handle(typ string, h Handler) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.handlers[typ] = h
+}
+
+// Send sends a one-way message
+func (e *Endpoint) Send(to Address, typ string, v interface{}) error {
+	return e.SendWith(e.codec, to, typ, v)
+}
+
+// SendWith sends a one-way message encoded with codec rather than
+// the endpoint codec, e.g. protobuf messages with Proto.
+func (e *Endpoint) SendWith(codec Codec, to Address, typ string, v interface{}) error {
+	env, err := e.envelope(codec, typ, v)
+	if err != nil {
+		return err
+	}
+	return e.post(to, env)
+}
+
+// Request sends a request and waits up to timeout for the reply,
+// which is decoded into reply unless it is nil.
+func (e *Endpoint) Request(to Address, typ string, v, reply interface{}, timeout time.Duration) error {
+	env, err := e.envelope(e.codec, typ, v)
+	if err != nil {
+		return err
+	}
+	replies := make(chan *envelope, 1)
+	e.mu.Lock()
+	e.pending[env.ID] = replies
+	e.mu.Unlock()
+	defer func() {
+		e.mu.Lock()
+		delete(e.pending, env.ID)
+		e.mu.Unlock()
+	}()
+
+	if err := e.post(to, env); err != nil {
+		return err
+	}
+
+	select {
+	case r := <-replies:
+		if r.Error != "" {
+			return fmt.Errorf("%s", r.Error)
+		}
+		if reply == nil {
+			return nil
+		}
+		codec, err := codecOf(r.Codec)
+		if err != nil {
+			return err
+		}
+		return codec.Unmarshal(r.Payload, reply)
+	case <-time.After(timeout):
+		return fmt.Errorf("message: no reply to %s %s within %s", typ, env.ID, timeout)
+	}
+}
+
+// Receive handles the data of a MESSAGE event: replies complete the
+// pending request, other messages go to the handler of their type.
+// Handlers run on their own goroutine so they may send requests.
+func (e *Endpoint) Receive(from Address, data []byte) error {
+	env := new(envelope)
+	if err := json.Unmarshal(data, env); err != nil {
+		return fmt.Errorf("message: invalid envelope: %s", err)
+	}
+
+	if env.InReplyTo != "" {
+		e.mu.Lock()
+		replies, ok := e.pending[env.InReplyTo]
+		e.mu.Unlock()
+		if !ok {
+			return fmt.Errorf("message: unexpected reply to %s", env.InReplyTo)
+		}
+		select {
+		case replies <- env:
+		default:
+			return fmt.Errorf("message: duplicate reply to %s", env.InReplyTo)
+		}
+		return nil
+	}
+
+	codec, err := codecOf(env.Codec)
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	h, ok := e.handlers[env.Type]
+	e.mu.Unlock()
+	if !ok {
+		e.reply(from, env, nil, fmt.Errorf("no handler for message type %s", env.Type))
+		return fmt.Errorf("message: no handler for message type %s", env.Type)
+	}
+
+	msg := &Message{From: from, Type: env.Type, ID: env.ID, env: env, codec: codec}
+	go func() {
+		result, err := h(msg)
+		if result != nil || err != nil {
+			e.reply(from, env, result, err)
+		}
+	}()
+	return nil
+}
+
+func (e *Endpoint) reply(to Address, req *envelope, result interface{}, herr error) {
+	env, err := e.envelope(e.codec, req.Type, result)
+	if err != nil {
+		env = &envelope{ID: e.nextID(), Type: req.Type, Codec: e.codec.Name(), Error: err.Error()}
+	}
+	env.InReplyTo = req.ID
+	if herr != nil {
+		env.Payload = nil
+		env.Error = herr.Error()
+	}
+	if err := e.post(to, env); err != nil {
+		log.Println("Unable to reply to message ", req.ID, ": ", err)
+	}
+}
+
+func (e *Endpoint) envelope(codec Codec, typ string, v interface{}) (*envelope, error) {
+	env := &envelope{ID: e.nextID(), Type: typ, Codec: codec.Name()}
+	if v != nil {
+		payload, err := codec.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		env.Payload = payload
+	}
+	return env, nil
+}
+
+func (e *Endpoint) post(to Address, env *envelope) error {
+	data, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+	return e.send(to, data)
+}
+
+// nextID returns a correlation ID unique to this endpoint
+func (e *Endpoint) nextID() string {
+	return fmt.Sprintf("%s-%d", e.prefix, atomic.AddUint64(&e.seq, 1))
+}
@@ -0,0 +1,303 @@
+// Package operation composes the operations accepted on an offer,
+// such as reserving resources, creating volumes on them and launching
+// tasks on those volumes, into a single validated ACCEPT call.
+package operation
+
+import (
+	"fmt"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/vladimirvivien/mesos-http/mesos/mesos"
+	"github.com/vladimirvivien/mesos-http/mesos/sched"
+)
+
+// Plan is an ordered list of operations on an offer. Each operation
+// is validated against the resources the previous ones left, as the
+// master applies them in order.
+type Plan struct {
+	offer      *mesos.Offer
+	remaining  []*mesos.Resource
+	operations []*mesos.Offer_Operation
+	tasks      map[string]bool
+	executors  map[string]bool
+}
+
+// New returns an empty plan for offer
+func New(offer *mesos.Offer) *Plan {
+	p := &Plan{
+		offer:     offer,
+		tasks:     make(map[string]bool),
+		executors: make(map[string]bool),
+	}
+	for _, res := range offer.GetResources() {
+		p.remaining = add(p.remaining, res)
+	}
+	return p
+}
+
+// Offer returns the offer of the plan
+func (p *Plan) Offer() *mesos.Offer {
+	return p.offer
+}
+
+// Remaining returns the resources left by the planned operations.
+// The resources must not be modified.
+func (p *Plan) Remaining() []*mesos.Resource {
+	return append([]*mesos.Resource(nil), p.remaining...)
+}
+
+// Operations returns the planned operations in order
+func (p *Plan) Operations() []*mesos.Offer_Operation {
+	return append([]*mesos.Offer_Operation(nil), p.operations...)
+}
+
+// Reserve plans the reservation of resources, which name the role
+// and reservation they are reserved with.
+func (p *Plan) Reserve(resources ...*mesos.Resource) error {
+	return p.apply(&mesos.Offer_Operation{
+		Type:    mesos.Offer_Operation_RESERVE.Enum(),
+		Reserve: &mesos.Offer_Operation_Reserve{Resources: resources},
+	})
+}
+
+// Unreserve plans the release of reserved resources
+func (p *Plan) Unreserve(resources ...*mesos.Resource) error {
+	return p.apply(&mesos.Offer_Operation{
+		Type:      mesos.Offer_Operation_UNRESERVE.Enum(),
+		Unreserve: &mesos.Offer_Operation_Unreserve{Resources: resources},
+	})
+}
+
+// Create plans the creation of persistent volumes on reserved disk
+func (p *Plan) Create(volumes ...*mesos.Resource) error {
+	return p.apply(&mesos.Offer_Operation{
+		Type:   mesos.Offer_Operation_CREATE.Enum(),
+		Create: &mesos.Offer_Operation_Create{Volumes: volumes},
+	})
+}
+
+// Destroy plans the destruction of persistent volumes
+func (p *Plan) Destroy(volumes ...*mesos.Resource) error {
+	return p.apply(&mesos.Offer_Operation{
+		Type:    mesos.Offer_Operation_DESTROY.Enum(),
+		Destroy: &mesos.Offer_Operation_Destroy{Volumes: volumes},
+	})
+}
+
+// Launch plans the launch of tasks. Tasks launched one after the
+// other are merged into a single operation.
+func (p *Plan) Launch(tasks ...*mesos.TaskInfo) error {
+	return p.apply(&mesos.Offer_Operation{
+		Type:   mesos.Offer_Operation_LAUNCH.Enum(),
+		Launch: &mesos.Offer_Operation_Launch{TaskInfos: tasks},
+	})
+}
+
+// Accept returns the call accepting the offer with the planned
+// operations. An empty plan declines the offer.
+func (p *Plan) Accept(frameworkID *mesos.FrameworkID) *sched.Call {
+	return &sched.Call{
+		FrameworkId: frameworkID,
+		Type:        sched.Call_ACCEPT.Enum(),
+		Accept: &sched.Call_Accept{
+			OfferIds:   []*mesos.OfferID{p.offer.GetId()},
+			Operations: p.Operations(),
+		},
+	}
+}
+
+// Validate returns an error if operations cannot be applied in
+// order to offer.
+func Validate(offer *mesos.Offer, operations []*mesos.Offer_Operation) error {
+	p := New(offer)
+	for i, op := range operations {
+		if err := p.apply(op); err != nil {
+			return fmt.Errorf("operation %d (%s): %s", i, op.GetType(), err)
+		}
+	}
+	return nil
+}
+
+// apply validates op against the remaining resources and appends it.
+// The plan is left unchanged if op is invalid; empty operations are
+// skipped.
+func (p *Plan) apply(op *mesos.Offer_Operation) error {
+	remaining := p.Remaining()
+	var err error
+	switch op.GetType() {
+	case mesos.Offer_Operation_RESERVE:
+		if len(op.GetReserve().GetResources()) == 0 {
+			return nil
+		}
+		remaining, err = reserve(remaining, op.GetReserve().GetResources())
+	case mesos.Offer_Operation_UNRESERVE:
+		if len(op.GetUnreserve().GetResources()) == 0 {
+			return nil
+		}
+		remaining, err = unreserve(remaining, op.GetUnreserve().GetResources())
+	case mesos.Offer_Operation_CREATE:
+		if len(op.GetCreate().GetVolumes()) == 0 {
+			return nil
+		}
+		remaining, err = create(remaining, op.GetCreate().GetVolumes())
+	case mesos.Offer_Operation_DESTROY:
+		if len(op.GetDestroy().GetVolumes()) == 0 {
+			return nil
+		}
+		remaining, err = destroy(remaining, op.GetDestroy().GetVolumes())
+	case mesos.Offer_Operation_LAUNCH:
+		if len(op.GetLaunch().GetTaskInfos()) == 0 {
+			return nil
+		}
+		return p.launch(remaining, op.GetLaunch().GetTaskInfos())
+	default:
+		return fmt.Errorf("operation: unsupported operation %s", op.GetType())
+	}
+	if err != nil {
+		return err
+	}
+	p.remaining = remaining
+	p.operations = append(p.operations, op)
+	return nil
+}
+
+func reserve(remaining, resources []*mesos.Resource) ([]*mesos.Resource, error) {
+	var err error
+	for _, res := range resources {
+		if res.GetRole() == "*" || res.Reservation == nil {
+			return nil, fmt.Errorf("operation: %s reserved without role or reservation", describe(res))
+		}
+		if res.Disk != nil {
+			return nil, fmt.Errorf("operation: %s reserved with disk info", describe(res))
+		}
+		if remaining, err = subtract(remaining, unreserved(res)); err != nil {
+			return nil, err
+		}
+		remaining = add(remaining, res)
+	}
+	return remaining, nil
+}
+
+func unreserve(remaining, resources []*mesos.Resource) ([]*mesos.Resource, error) {
+	var err error
+	for _, res := range resources {
+		if res.Reservation == nil {
+			return nil, fmt.Errorf("operation: %s is not dynamically reserved", describe(res))
+		}
+		if res.Disk != nil {
+			return nil, fmt.Errorf("operation: volume %s must be destroyed before it is unreserved", describe(res))
+		}
+		if remaining, err = subtract(remaining, res); err != nil {
+			return nil, err
+		}
+		remaining = add(remaining, unreserved(res))
+	}
+	return remaining, nil
+}
+
+func create(remaining, volumes []*mesos.Resource) ([]*mesos.Resource, error) {
+	var err error
+	for _, vol := range volumes {
+		if vol.GetName() != "disk" || vol.GetDisk().GetPersistence().GetId() == "" {
+			return nil, fmt.Errorf("operation: %s is not a persistent volume", describe(vol))
+		}
+		if vol.GetRole() == "*" {
+			return nil, fmt.Errorf("operation: volume %s is on unreserved disk", describe(vol))
+		}
+		for _, r := range remaining {
+			if r.GetDisk().GetPersistence().GetId() == vol.GetDisk().GetPersistence().GetId() &&
+				r.GetRole() == vol.GetRole() {
+				return nil, fmt.Errorf("operation: volume %s already exists", describe(vol))
+			}
+		}
+		if remaining, err = subtract(remaining, plain(vol)); err != nil {
+			return nil, err
+		}
+		remaining = add(remaining, vol)
+	}
+	return remaining, nil
+}
+
+func destroy(remaining, volumes []*mesos.Resource) ([]*mesos.Resource, error) {
+	var err error
+	for _, vol := range volumes {
+		if vol.GetDisk().GetPersistence().GetId() == "" {
+			return nil, fmt.Errorf("operation: %s is not a persistent volume", describe(vol))
+		}
+		if remaining, err = subtract(remaining, vol); err != nil {
+			return nil, err
+		}
+		remaining = add(remaining, plain(vol))
+	}
+	return remaining, nil
+}
+
+// launch appends tasks to the last operation if it is a launch
+func (p *Plan) launch(remaining []*mesos.Resource, tasks []*mesos.TaskInfo) error {
+	var err error
+	ids := make(map[string]bool)
+	executors := make(map[string]bool)
+	for _, task := range tasks {
+		id := task.GetTaskId().GetValue()
+		if id == "" || p.tasks[id] || ids[id] {
+			return fmt.Errorf("operation: missing or duplicate task ID %q", id)
+		}
+		ids[id] = true
+		if agentID := task.GetAgentId().GetValue(); agentID != p.offer.GetAgentId().GetValue() {
+			return fmt.Errorf("operation: task %s is for agent %s, not %s", id, agentID, p.offer.GetAgentId().GetValue())
+		}
+		for _, res := range task.GetResources() {
+			if remaining, err = subtract(remaining, res); err != nil {
+				return fmt.Errorf("%s for task %s", err, id)
+			}
+		}
+		// an executor runs its tasks with the resources it was
+		// launched with only once.
+		if exec := task.GetExecutor(); exec != nil {
+			execID := exec.GetExecutorId().GetValue()
+			if p.executors[execID] || executors[execID] {
+				continue
+			}
+			executors[execID] = true
+			for _, res := range exec.GetResources() {
+				if remaining, err = subtract(remaining, res); err != nil {
+					return fmt.Errorf("%s for executor %s", err, execID)
+				}
+			}
+		}
+	}
+
+	for id := range ids {
+		p.tasks[id] = true
+	}
+	for id := range executors {
+		p.executors[id] = true
+	}
+	p.remaining = remaining
+	if n := len(p.operations); n > 0 && p.operations[n-1].GetType() == mesos.Offer_Operation_LAUNCH {
+		last := proto.Clone(p.operations[n-1]).(*mesos.Offer_Operation)
+		last.Launch.TaskInfos = append(last.Launch.TaskInfos, tasks...)
+		p.operations[n-1] = last
+		return nil
+	}
+	p.operations = append(p.operations, &mesos.Offer_Operation{
+		Type:   mesos.Offer_Operation_LAUNCH.Enum(),
+		Launch: &mesos.Offer_Operation_Launch{TaskInfos: tasks},
+	})
+	return nil
+}
+
+// unreserved returns res without its role and reservation
+func unreserved(res *mesos.Resource) *mesos.Resource {
+	r := proto.Clone(res).(*mesos.Resource)
+	r.Role = nil
+	r.Reservation = nil
+	return r
+}
+
+// plain returns the reserved disk a volume is created on
+func plain(vol *mesos.Resource) *mesos.Resource {
+	r := proto.Clone(vol).(*mesos.Resource)
+	r.Disk = nil
+	return r
+}
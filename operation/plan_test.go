@@ -0,0 +1,262 @@
+package operation
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/vladimirvivien/mesos-http/mesos/mesos"
+)
+
+func scalar(name, role string, value float64) *mesos.Resource {
+	res := &mesos.Resource{
+		Name:   proto.String(name),
+		Type:   mesos.Value_SCALAR.Enum(),
+		Scalar: &mesos.Value_Scalar{Value: proto.Float64(value)},
+		Role:   proto.String(role),
+	}
+	if role != "*" {
+		res.Reservation = &mesos.Resource_ReservationInfo{Principal: proto.String("p")}
+	}
+	return res
+}
+
+func ports(ranges ...[2]uint64) *mesos.Resource {
+	res := &mesos.Resource{
+		Name:   proto.String("ports"),
+		Type:   mesos.Value_RANGES.Enum(),
+		Ranges: new(mesos.Value_Ranges),
+		Role:   proto.String("*"),
+	}
+	for _, r := range ranges {
+		res.Ranges.Range = append(res.Ranges.Range, &mesos.Value_Range{
+			Begin: proto.Uint64(r[0]),
+			End:   proto.Uint64(r[1]),
+		})
+	}
+	return res
+}
+
+func volume(id string, size float64) *mesos.Resource {
+	vol := scalar("disk", "r", size)
+	vol.Disk = &mesos.Resource_DiskInfo{
+		Persistence: &mesos.Resource_DiskInfo_Persistence{Id: proto.String(id)},
+		Volume: &mesos.Volume{
+			ContainerPath: proto.String("data"),
+			Mode:          mesos.Volume_RW.Enum(),
+		},
+	}
+	return vol
+}
+
+func task(id, agentID string, resources ...*mesos.Resource) *mesos.TaskInfo {
+	return &mesos.TaskInfo{
+		Name:      proto.String(id),
+		TaskId:    &mesos.TaskID{Value: proto.String(id)},
+		AgentId:   &mesos.AgentID{Value: proto.String(agentID)},
+		Resources: resources,
+	}
+}
+
+func offer(resources ...*mesos.Resource) *mesos.Offer {
+	return &mesos.Offer{
+		Id:          &mesos.OfferID{Value: proto.String("o1")},
+		FrameworkId: &mesos.FrameworkID{Value: proto.String("f1")},
+		AgentId:     &mesos.AgentID{Value: proto.String("a1")},
+		Hostname:    proto.String("h1"),
+		Resources:   resources,
+	}
+}
+
+// remaining describes the resources left by a plan, sorted
+func remaining(p *Plan) string {
+	var s []string
+	for _, res := range p.Remaining() {
+		s = append(s, describe(res))
+	}
+	sort.Strings(s)
+	return strings.Join(s, " ")
+}
+
+func TestPlan(t *testing.T) {
+	type step struct {
+		apply func(p *Plan) error
+		ok    bool
+	}
+	launch := func(tasks ...*mesos.TaskInfo) func(p *Plan) error {
+		return func(p *Plan) error { return p.Launch(tasks...) }
+	}
+	tests := []struct {
+		name      string
+		offer     *mesos.Offer
+		steps     []step
+		remaining string
+		ops       []mesos.Offer_Operation_Type
+	}{
+		{
+			name:  "reserve create launch",
+			offer: offer(scalar("cpus", "*", 4), scalar("mem", "*", 1024), scalar("disk", "*", 500)),
+			steps: []step{
+				{func(p *Plan) error {
+					return p.Reserve(scalar("cpus", "r", 2), scalar("mem", "r", 512), scalar("disk", "r", 200))
+				}, true},
+				{func(p *Plan) error { return p.Create(volume("db", 100)) }, true},
+				{launch(task("t1", "a1", scalar("cpus", "r", 1), scalar("mem", "r", 256), volume("db", 100))), true},
+			},
+			remaining: "cpus(*):2 cpus(r):1 disk(*):300 disk(r):100 mem(*):512 mem(r):256",
+			ops: []mesos.Offer_Operation_Type{
+				mesos.Offer_Operation_RESERVE, mesos.Offer_Operation_CREATE, mesos.Offer_Operation_LAUNCH,
+			},
+		},
+		{
+			name:  "launches merged",
+			offer: offer(scalar("cpus", "*", 2)),
+			steps: []step{
+				{launch(task("t1", "a1", scalar("cpus", "*", 1))), true},
+				{launch(task("t2", "a1", scalar("cpus", "*", 1))), true},
+			},
+			remaining: "",
+			ops:       []mesos.Offer_Operation_Type{mesos.Offer_Operation_LAUNCH},
+		},
+		{
+			name:  "port range split",
+			offer: offer(ports([2]uint64{31000, 31010})),
+			steps: []step{
+				{launch(task("t1", "a1", ports([2]uint64{31003, 31004}))), true},
+				{launch(task("t2", "a1", ports([2]uint64{31000, 31000}, [2]uint64{31010, 31010}))), true},
+			},
+			remaining: "ports(*):[31001-31002,31005-31009]",
+			ops:       []mesos.Offer_Operation_Type{mesos.Offer_Operation_LAUNCH},
+		},
+		{
+			name:  "port taken twice",
+			offer: offer(ports([2]uint64{31000, 31010})),
+			steps: []step{
+				{launch(task("t1", "a1", ports([2]uint64{31003, 31004}))), true},
+				{launch(task("t2", "a1", ports([2]uint64{31004, 31005}))), false},
+			},
+			remaining: "ports(*):[31000-31002,31005-31010]",
+			ops:       []mesos.Offer_Operation_Type{mesos.Offer_Operation_LAUNCH},
+		},
+		{
+			name:  "unreserve destroyed volume",
+			offer: offer(volume("db", 100), scalar("disk", "r", 50)),
+			steps: []step{
+				{func(p *Plan) error { return p.Unreserve(volume("db", 100)) }, false},
+				{func(p *Plan) error { return p.Destroy(volume("db", 100)) }, true},
+				{func(p *Plan) error { return p.Unreserve(scalar("disk", "r", 150)) }, true},
+			},
+			remaining: "disk(*):150",
+			ops: []mesos.Offer_Operation_Type{
+				mesos.Offer_Operation_DESTROY, mesos.Offer_Operation_UNRESERVE,
+			},
+		},
+		{
+			name:  "failures leave the plan unchanged",
+			offer: offer(scalar("cpus", "*", 1), scalar("disk", "*", 100), scalar("disk", "r", 100)),
+			steps: []step{
+				// more than offered
+				{launch(task("t1", "a1", scalar("cpus", "*", 2))), false},
+				{func(p *Plan) error { return p.Reserve(scalar("cpus", "r", 2)) }, false},
+				// reserved resources need a role
+				{func(p *Plan) error { return p.Reserve(scalar("cpus", "*", 1)) }, false},
+				// volumes need reserved disk
+				{func(p *Plan) error {
+					vol := volume("db", 50)
+					vol.Role, vol.Reservation = proto.String("*"), nil
+					return p.Create(vol)
+				}, false},
+				{func(p *Plan) error { return p.Destroy(volume("db", 50)) }, false},
+				{func(p *Plan) error { return p.Unreserve(scalar("disk", "*", 10)) }, false},
+				// wrong agent, missing or duplicate IDs
+				{launch(task("t1", "a2", scalar("cpus", "*", 1))), false},
+				{launch(task("", "a1", scalar("cpus", "*", 1))), false},
+				{launch(task("t1", "a1"), task("t1", "a1")), false},
+				// a failed batch launches none of its tasks
+				{launch(task("t1", "a1", scalar("cpus", "*", 1)), task("t2", "a1", scalar("cpus", "*", 1))), false},
+			},
+			remaining: "cpus(*):1 disk(*):100 disk(r):100",
+		},
+		{
+			name:  "volume created twice",
+			offer: offer(scalar("disk", "r", 200)),
+			steps: []step{
+				{func(p *Plan) error { return p.Create(volume("db", 100)) }, true},
+				{func(p *Plan) error { return p.Create(volume("db", 100)) }, false},
+			},
+			remaining: "disk(r):100 disk(r)[db]:100",
+			ops:       []mesos.Offer_Operation_Type{mesos.Offer_Operation_CREATE},
+		},
+		{
+			name:  "executor resources counted once",
+			offer: offer(scalar("cpus", "*", 2)),
+			steps: []step{
+				{func(p *Plan) error {
+					exec := &mesos.ExecutorInfo{
+						ExecutorId: &mesos.ExecutorID{Value: proto.String("e1")},
+						Command:    &mesos.CommandInfo{Value: proto.String("exec")},
+						Resources:  []*mesos.Resource{scalar("cpus", "*", 0.5)},
+					}
+					t1 := task("t1", "a1", scalar("cpus", "*", 0.5))
+					t2 := task("t2", "a1", scalar("cpus", "*", 0.5))
+					t1.Executor, t2.Executor = exec, exec
+					return p.Launch(t1, t2)
+				}, true},
+			},
+			remaining: "cpus(*):0.5",
+			ops:       []mesos.Offer_Operation_Type{mesos.Offer_Operation_LAUNCH},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := New(test.offer)
+			for i, s := range test.steps {
+				err := s.apply(p)
+				if (err == nil) != s.ok {
+					t.Fatalf("step %d: got error %v, want ok %v", i, err, s.ok)
+				}
+			}
+			if got := remaining(p); got != test.remaining {
+				t.Errorf("remaining %q, want %q", got, test.remaining)
+			}
+			var ops []mesos.Offer_Operation_Type
+			for _, op := range p.Operations() {
+				ops = append(ops, op.GetType())
+			}
+			if len(ops) != len(test.ops) {
+				t.Fatalf("operations %v, want %v", ops, test.ops)
+			}
+			for i := range ops {
+				if ops[i] != test.ops[i] {
+					t.Fatalf("operations %v, want %v", ops, test.ops)
+				}
+			}
+			if err := Validate(test.offer, p.Operations()); err != nil {
+				t.Errorf("planned operations do not validate: %s", err)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	o := offer(scalar("cpus", "*", 1), scalar("disk", "*", 100))
+	reserve := &mesos.Offer_Operation{
+		Type:    mesos.Offer_Operation_RESERVE.Enum(),
+		Reserve: &mesos.Offer_Operation_Reserve{Resources: []*mesos.Resource{scalar("disk", "r", 100)}},
+	}
+	create := &mesos.Offer_Operation{
+		Type:   mesos.Offer_Operation_CREATE.Enum(),
+		Create: &mesos.Offer_Operation_Create{Volumes: []*mesos.Resource{volume("db", 100)}},
+	}
+	if err := Validate(o, []*mesos.Offer_Operation{reserve, create}); err != nil {
+		t.Errorf("reserve then create: %s", err)
+	}
+	// operations are applied in order
+	if err := Validate(o, []*mesos.Offer_Operation{create, reserve}); err == nil {
+		t.Error("create before reserve validated")
+	}
+	if err := Validate(o, []*mesos.Offer_Operation{{Type: mesos.Offer_Operation_Type(0).Enum()}}); err == nil {
+		t.Error("unknown operation validated")
+	}
+}
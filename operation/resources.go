@@ -0,0 +1,222 @@
+package operation
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/vladimirvivien/mesos-http/mesos/mesos"
+)
+
+// same returns true if a and b are the same kind of resource, whose
+// amounts can be added to and subtracted from one another.
+func same(a, b *mesos.Resource) bool {
+	return a.GetName() == b.GetName() &&
+		a.GetType() == b.GetType() &&
+		a.GetRole() == b.GetRole() &&
+		proto.Equal(a.GetReservation(), b.GetReservation()) &&
+		proto.Equal(a.GetDisk(), b.GetDisk()) &&
+		(a.Revocable == nil) == (b.Revocable == nil)
+}
+
+// add returns pool with res added. The resources of pool are never
+// modified, they are replaced.
+func add(pool []*mesos.Resource, res *mesos.Resource) []*mesos.Resource {
+	for i, r := range pool {
+		if !same(r, res) {
+			continue
+		}
+		sum := proto.Clone(r).(*mesos.Resource)
+		switch r.GetType() {
+		case mesos.Value_SCALAR:
+			sum.Scalar.Value = proto.Float64(round(r.GetScalar().GetValue() + res.GetScalar().GetValue()))
+		case mesos.Value_RANGES:
+			sum.Ranges = toRanges(append(fromRanges(r.GetRanges()), fromRanges(res.GetRanges())...))
+		case mesos.Value_SET:
+			sum.Set.Item = union(r.GetSet().GetItem(), res.GetSet().GetItem())
+		}
+		pool[i] = sum
+		return pool
+	}
+	return append(pool, proto.Clone(res).(*mesos.Resource))
+}
+
+// subtract returns pool with res removed, or an error if pool
+// holds less than res.
+func subtract(pool []*mesos.Resource, res *mesos.Resource) ([]*mesos.Resource, error) {
+	for i, r := range pool {
+		if !same(r, res) {
+			continue
+		}
+		left, ok := minus(r, res)
+		if !ok {
+			break
+		}
+		if left == nil {
+			return append(pool[:i:i], pool[i+1:]...), nil
+		}
+		pool[i] = left
+		return pool, nil
+	}
+	return nil, fmt.Errorf("operation: %s not available", describe(res))
+}
+
+// minus returns r minus res, nil if nothing is left. It returns
+// false if r holds less than res.
+func minus(r, res *mesos.Resource) (*mesos.Resource, bool) {
+	left := proto.Clone(r).(*mesos.Resource)
+	switch r.GetType() {
+	case mesos.Value_SCALAR:
+		v := round(r.GetScalar().GetValue() - res.GetScalar().GetValue())
+		if v < 0 {
+			return nil, false
+		}
+		if v == 0 {
+			return nil, true
+		}
+		left.Scalar.Value = proto.Float64(v)
+	case mesos.Value_RANGES:
+		ranges, ok := without(fromRanges(r.GetRanges()), fromRanges(res.GetRanges()))
+		if !ok {
+			return nil, false
+		}
+		if len(ranges) == 0 {
+			return nil, true
+		}
+		left.Ranges = toRanges(ranges)
+	case mesos.Value_SET:
+		items, ok := difference(r.GetSet().GetItem(), res.GetSet().GetItem())
+		if !ok {
+			return nil, false
+		}
+		if len(items) == 0 {
+			return nil, true
+		}
+		left.Set.Item = items
+	}
+	return left, true
+}
+
+// describe returns a short form of a resource for error messages,
+// e.g. disk(db)[pg]:100
+func describe(res *mesos.Resource) string {
+	s := fmt.Sprintf("%s(%s)", res.GetName(), res.GetRole())
+	if id := res.GetDisk().GetPersistence().GetId(); id != "" {
+		s += "[" + id + "]"
+	}
+	switch res.GetType() {
+	case mesos.Value_SCALAR:
+		s += fmt.Sprintf(":%v", res.GetScalar().GetValue())
+	case mesos.Value_RANGES:
+		var ranges []string
+		for _, r := range res.GetRanges().GetRange() {
+			ranges = append(ranges, fmt.Sprintf("%d-%d", r.GetBegin(), r.GetEnd()))
+		}
+		s += ":[" + strings.Join(ranges, ",") + "]"
+	case mesos.Value_SET:
+		s += ":{" + strings.Join(res.GetSet().GetItem(), ",") + "}"
+	}
+	return s
+}
+
+// round rounds a scalar to the three decimals Mesos keeps
+func round(v float64) float64 {
+	return math.Round(v*1000) / 1000
+}
+
+type interval struct{ begin, end uint64 }
+
+func fromRanges(ranges *mesos.Value_Ranges) []interval {
+	var intervals []interval
+	for _, r := range ranges.GetRange() {
+		if r.GetBegin() <= r.GetEnd() {
+			intervals = append(intervals, interval{r.GetBegin(), r.GetEnd()})
+		}
+	}
+	return intervals
+}
+
+// toRanges returns intervals sorted with overlapping and adjacent
+// intervals merged.
+func toRanges(intervals []interval) *mesos.Value_Ranges {
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i].begin < intervals[j].begin })
+	ranges := new(mesos.Value_Ranges)
+	for _, iv := range intervals {
+		n := len(ranges.Range)
+		if n > 0 && iv.begin <= ranges.Range[n-1].GetEnd()+1 {
+			if iv.end > ranges.Range[n-1].GetEnd() {
+				ranges.Range[n-1].End = proto.Uint64(iv.end)
+			}
+			continue
+		}
+		ranges.Range = append(ranges.Range, &mesos.Value_Range{
+			Begin: proto.Uint64(iv.begin),
+			End:   proto.Uint64(iv.end),
+		})
+	}
+	return ranges
+}
+
+// without returns pool minus the intervals of taken, false if
+// taken is not within pool.
+func without(pool, taken []interval) ([]interval, bool) {
+	for _, t := range taken {
+		found := false
+		for i, p := range pool {
+			if t.begin < p.begin || t.end > p.end {
+				continue
+			}
+			var split []interval
+			if p.begin < t.begin {
+				split = append(split, interval{p.begin, t.begin - 1})
+			}
+			if t.end < p.end {
+				split = append(split, interval{t.end + 1, p.end})
+			}
+			pool = append(pool[:i:i], append(split, pool[i+1:]...)...)
+			found = true
+			break
+		}
+		if !found {
+			return nil, false
+		}
+	}
+	return pool, true
+}
+
+func union(a, b []string) []string {
+	items := append([]string(nil), a...)
+	for _, item := range b {
+		if !contains(items, item) {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// difference returns a minus b, false if b is not within a
+func difference(a, b []string) ([]string, bool) {
+	var items []string
+	for _, item := range a {
+		if !contains(b, item) {
+			items = append(items, item)
+		}
+	}
+	for _, item := range b {
+		if !contains(a, item) {
+			return nil, false
+		}
+	}
+	return items, true
+}
+
+func contains(items []string, item string) bool {
+	for _, i := range items {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
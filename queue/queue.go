@@ -0,0 +1,149 @@
+package queue
+
+import (
+	"container/heap"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/vladimirvivien/mesos-http/constraint"
+	"github.com/vladimirvivien/mesos-http/container"
+	"github.com/vladimirvivien/mesos-http/mesos/mesos"
+)
+
+// Task is a unit of work waiting for an offer
+type Task struct {
+	ID          string
+	Name        string
+	Priority    int
+	Cpus        float64
+	Mem         float64
+	Constraints []*constraint.Constraint
+	Labels      map[string]string
+	URIs        []*mesos.CommandInfo_URI
+	Container   *container.Spec
+	Volume      string
+
+	seq   uint64
+	index int
+}
+
+// Queue is a concurrency-safe queue of pending tasks. Tasks with a
+// higher priority are dequeued first; tasks with the same priority
+// are dequeued in submission order.
+type Queue struct {
+	mu    sync.Mutex
+	tasks taskHeap
+	byID  map[string]*Task
+	seq   uint64
+}
+
+// New returns an empty queue
+func New() *Queue {
+	return &Queue{byID: make(map[string]*Task)}
+}
+
+// Push queues a task. It fails if a task with the same ID is pending.
+func (q *Queue) Push(t *Task) error {
+	if t.ID == "" {
+		return fmt.Errorf("queue: task has no ID")
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, ok := q.byID[t.ID]; ok {
+		return fmt.Errorf("queue: task %s already queued", t.ID)
+	}
+	q.seq++
+	t.seq = q.seq
+	heap.Push(&q.tasks, t)
+	q.byID[t.ID] = t
+	return nil
+}
+
+// Pop removes and returns the next task or nil if the queue is empty
+func (q *Queue) Pop() *Task {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.tasks) == 0 {
+		return nil
+	}
+	t := heap.Pop(&q.tasks).(*Task)
+	delete(q.byID, t.ID)
+	return t
+}
+
+// Remove removes a pending task. It returns false if the task is
+// not queued, for instance because it was already dequeued.
+func (q *Queue) Remove(id string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	t, ok := q.byID[id]
+	if !ok {
+		return false
+	}
+	heap.Remove(&q.tasks, t.index)
+	delete(q.byID, id)
+	return true
+}
+
+// Get returns the pending task with the given ID
+func (q *Queue) Get(id string) (*Task, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	t, ok := q.byID[id]
+	return t, ok
+}
+
+// Len returns the number of pending tasks
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.tasks)
+}
+
+// Tasks returns a snapshot of the pending tasks in dequeue order
+func (q *Queue) Tasks() []*Task {
+	q.mu.Lock()
+	tasks := make([]*Task, len(q.tasks))
+	copy(tasks, q.tasks)
+	q.mu.Unlock()
+
+	sort.Slice(tasks, func(i, j int) bool {
+		return before(tasks[i], tasks[j])
+	})
+	return tasks
+}
+
+func before(a, b *Task) bool {
+	if a.Priority != b.Priority {
+		return a.Priority > b.Priority
+	}
+	return a.seq < b.seq
+}
+
+// taskHeap implements heap.Interface
+type taskHeap []*Task
+
+func (h taskHeap) Len() int           { return len(h) }
+func (h taskHeap) Less(i, j int) bool { return before(h[i], h[j]) }
+func (h taskHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *taskHeap) Push(x interface{}) {
+	t := x.(*Task)
+	t.index = len(*h)
+	*h = append(*h, t)
+}
+
+func (h *taskHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	t := old[n-1]
+	old[n-1] = nil
+	t.index = -1
+	*h = old[:n-1]
+	return t
+}
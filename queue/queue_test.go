@@ -0,0 +1,136 @@
+package queue
+
+import (
+	"reflect"
+	"testing"
+)
+
+func push(t *testing.T, q *Queue, tasks ...*Task) {
+	t.Helper()
+	for _, task := range tasks {
+		if err := q.Push(task); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// drain pops every task and returns their IDs in dequeue order
+func drain(q *Queue) []string {
+	var ids []string
+	for task := q.Pop(); task != nil; task = q.Pop() {
+		ids = append(ids, task.ID)
+	}
+	return ids
+}
+
+func ids(tasks []*Task) []string {
+	ids := make([]string, len(tasks))
+	for i, task := range tasks {
+		ids[i] = task.ID
+	}
+	return ids
+}
+
+func TestPriorityOrder(t *testing.T) {
+	q := New()
+	push(t, q,
+		&Task{ID: "low", Priority: -1},
+		&Task{ID: "default"},
+		&Task{ID: "high", Priority: 5},
+		&Task{ID: "medium", Priority: 2},
+	)
+	want := []string{"high", "medium", "default", "low"}
+	if got := ids(q.Tasks()); !reflect.DeepEqual(got, want) {
+		t.Errorf("tasks %v, want %v", got, want)
+	}
+	if got := drain(q); !reflect.DeepEqual(got, want) {
+		t.Errorf("dequeued %v, want %v", got, want)
+	}
+	if q.Len() != 0 || q.Pop() != nil {
+		t.Error("drained queue not empty")
+	}
+}
+
+func TestFIFOWithinPriority(t *testing.T) {
+	q := New()
+	push(t, q,
+		&Task{ID: "a1", Priority: 1},
+		&Task{ID: "b1"},
+		&Task{ID: "a2", Priority: 1},
+		&Task{ID: "b2"},
+		&Task{ID: "a3", Priority: 1},
+	)
+	// a requeued task goes after the tasks of its priority
+	first := q.Pop()
+	push(t, q, first)
+	want := []string{"a2", "a3", "a1", "b1", "b2"}
+	if got := drain(q); !reflect.DeepEqual(got, want) {
+		t.Errorf("dequeued %v, want %v", got, want)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	q := New()
+	for _, id := range []string{"t1", "t2", "t3", "t4", "t5", "t6", "t7"} {
+		push(t, q, &Task{ID: id})
+	}
+	push(t, q, &Task{ID: "high", Priority: 9}, &Task{ID: "low", Priority: -9})
+
+	for _, step := range []struct {
+		id   string
+		ok   bool
+		left int
+	}{
+		{"t4", true, 8},
+		{"high", true, 7},
+		{"low", true, 6},
+		{"t4", false, 6},
+		{"unknown", false, 6},
+	} {
+		if ok := q.Remove(step.id); ok != step.ok {
+			t.Errorf("Remove(%s) = %v, want %v", step.id, ok, step.ok)
+		}
+		if _, ok := q.Get(step.id); ok {
+			t.Errorf("removed task %s still queued", step.id)
+		}
+		if n := q.Len(); n != step.left {
+			t.Errorf("Len after removing %s = %d, want %d", step.id, n, step.left)
+		}
+	}
+	// the heap stays ordered
+	want := []string{"t1", "t2", "t3", "t5", "t6", "t7"}
+	if got := drain(q); !reflect.DeepEqual(got, want) {
+		t.Errorf("dequeued %v, want %v", got, want)
+	}
+}
+
+func TestGetAndLen(t *testing.T) {
+	q := New()
+	push(t, q, &Task{ID: "t1", Name: "first"}, &Task{ID: "t2", Priority: 1})
+	if task, ok := q.Get("t1"); !ok || task.Name != "first" {
+		t.Errorf("Get(t1) = %+v, %v", task, ok)
+	}
+	if n := q.Len(); n != 2 {
+		t.Errorf("Len = %d, want 2", n)
+	}
+
+	if task := q.Pop(); task.ID != "t2" {
+		t.Errorf("dequeued %s, want t2", task.ID)
+	}
+	if _, ok := q.Get("t2"); ok {
+		t.Error("dequeued task still queued")
+	}
+	if n := q.Len(); n != 1 {
+		t.Errorf("Len = %d, want 1", n)
+	}
+
+	if err := q.Push(&Task{ID: "t1"}); err == nil {
+		t.Error("task queued twice")
+	}
+	if err := q.Push(&Task{}); err == nil {
+		t.Error("task without ID queued")
+	}
+	if n := q.Len(); n != 1 {
+		t.Errorf("Len = %d, want 1", n)
+	}
+}
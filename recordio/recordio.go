@@ -0,0 +1,52 @@
+package recordio
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Write writes one RecordIO record: the payload length in
+// decimal, a newline, then the payload.
+func Write(w io.Writer, data []byte) error {
+	if _, err := fmt.Fprintf(w, "%d\n", len(data)); err != nil {
+		return err
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+// Reader reads RecordIO records from a stream
+type Reader struct {
+	r *bufio.Reader
+}
+
+// NewReader returns a reader of the records in r
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+// Read returns the next record. It returns io.EOF at the end
+// of the stream and io.ErrUnexpectedEOF for a truncated record.
+func (r *Reader) Read() ([]byte, error) {
+	header, err := r.r.ReadString('\n')
+	if err != nil {
+		if err == io.EOF && header != "" {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	size, err := strconv.ParseUint(header[:len(header)-1], 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("recordio: invalid record length %q", header[:len(header)-1])
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r.r, data); err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return data, nil
+}
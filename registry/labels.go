@@ -0,0 +1,67 @@
+package registry
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/vladimirvivien/mesos-http/mesos/mesos"
+)
+
+// ParseLabels parses a comma-separated list of key=value pairs
+// such as "app=web,tier=frontend".
+func ParseLabels(s string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("registry: expecting key=value, got %q", pair)
+		}
+		labels[kv[0]] = kv[1]
+	}
+	return labels, nil
+}
+
+// ToLabels converts a label map to mesos Labels, sorted by key
+func ToLabels(m map[string]string) *mesos.Labels {
+	if len(m) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	labels := new(mesos.Labels)
+	for _, k := range keys {
+		labels.Labels = append(labels.Labels, &mesos.Label{
+			Key:   proto.String(k),
+			Value: proto.String(m[k]),
+		})
+	}
+	return labels
+}
+
+// MatchLabels returns true when labels contain every key=value
+// pair of the selector. An empty selector matches everything.
+func MatchLabels(labels *mesos.Labels, selector map[string]string) bool {
+	for k, v := range selector {
+		found := false
+		for _, l := range labels.GetLabels() {
+			if l.GetKey() == k && l.GetValue() == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,62 @@
+package registry
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLabels(t *testing.T) {
+	tests := []struct {
+		in   string
+		want map[string]string
+		ok   bool
+	}{
+		{"app=web,tier=frontend", map[string]string{"app": "web", "tier": "frontend"}, true},
+		{" app=web , tier=back,", map[string]string{"app": "web", "tier": "back"}, true},
+		{"url=http://h/?a=b", map[string]string{"url": "http://h/?a=b"}, true},
+		{"empty=", map[string]string{"empty": ""}, true},
+		{"", map[string]string{}, true},
+		{"app", nil, false},
+		{"=web", nil, false},
+		{"app=web,tier", nil, false},
+	}
+	for _, test := range tests {
+		got, err := ParseLabels(test.in)
+		if (err == nil) != test.ok {
+			t.Errorf("ParseLabels(%q) error %v, want ok %v", test.in, err, test.ok)
+			continue
+		}
+		if test.ok && !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ParseLabels(%q) = %v, want %v", test.in, got, test.want)
+		}
+	}
+}
+
+func TestMatchLabels(t *testing.T) {
+	labels := ToLabels(map[string]string{"tier": "front", "app": "web"})
+	if keys := []string{labels.Labels[0].GetKey(), labels.Labels[1].GetKey()}; keys[0] != "app" || keys[1] != "tier" {
+		t.Errorf("labels %v, want sorted by key", keys)
+	}
+	if ToLabels(nil) != nil {
+		t.Error("labels of an empty map")
+	}
+
+	tests := []struct {
+		selector map[string]string
+		want     bool
+	}{
+		{nil, true},
+		{map[string]string{"app": "web"}, true},
+		{map[string]string{"app": "web", "tier": "front"}, true},
+		{map[string]string{"app": "db"}, false},
+		{map[string]string{"app": "web", "zone": "a"}, false},
+	}
+	for _, test := range tests {
+		if got := MatchLabels(labels, test.selector); got != test.want {
+			t.Errorf("MatchLabels(%v) = %v, want %v", test.selector, got, test.want)
+		}
+	}
+	if MatchLabels(nil, map[string]string{"app": "web"}) {
+		t.Error("task without labels matched")
+	}
+}
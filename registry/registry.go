@@ -0,0 +1,290 @@
+package registry
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/vladimirvivien/mesos-http/mesos/mesos"
+)
+
+// Status is an entry in the status history of a task
+type Status struct {
+	State   mesos.TaskState
+	Reason  mesos.TaskStatus_Reason
+	Source  mesos.TaskStatus_Source
+	Message string
+	Healthy *bool
+	Time    time.Time
+}
+
+// Task is the registry record of a launched task
+type Task struct {
+	Info    *mesos.TaskInfo
+	AgentID string
+	State   mesos.TaskState
+	History []Status
+
+	// KillRequested is set when the framework asked to kill the task
+	KillRequested time.Time
+
+	Created time.Time
+	Updated time.Time
+}
+
+// ID returns the task ID value
+func (t *Task) ID() string {
+	return t.Info.GetTaskId().GetValue()
+}
+
+// Terminal returns true when the task reached a terminal state
+func (t *Task) Terminal() bool {
+	return IsTerminal(t.State)
+}
+
+// Latest returns the most recent status or nil if no update
+// was received yet.
+func (t *Task) Latest() *Status {
+	if len(t.History) == 0 {
+		return nil
+	}
+	return &t.History[len(t.History)-1]
+}
+
+func (t *Task) clone() *Task {
+	c := *t
+	c.History = append([]Status(nil), t.History...)
+	return &c
+}
+
+// IsTerminal returns true for states a task cannot leave
+func IsTerminal(state mesos.TaskState) bool {
+	switch state {
+	case mesos.TaskState_TASK_FINISHED,
+		mesos.TaskState_TASK_FAILED,
+		mesos.TaskState_TASK_KILLED,
+		mesos.TaskState_TASK_LOST,
+		mesos.TaskState_TASK_ERROR:
+		return true
+	}
+	return false
+}
+
+// stage orders the non-terminal states
+var stage = map[mesos.TaskState]int{
+	mesos.TaskState_TASK_STAGING:  0,
+	mesos.TaskState_TASK_STARTING: 1,
+	mesos.TaskState_TASK_RUNNING:  2,
+	mesos.TaskState_TASK_KILLING:  3,
+}
+
+// ValidTransition returns true when a task in state from can move
+// to state to. Terminal states are final, and a task never moves
+// back to an earlier stage (e.g. RUNNING to STAGING). Repeating the
+// current state is allowed, as health updates do.
+func ValidTransition(from, to mesos.TaskState) bool {
+	if IsTerminal(from) {
+		return false
+	}
+	if IsTerminal(to) {
+		return true
+	}
+	return stage[to] >= stage[from]
+}
+
+// Registry is a concurrency-safe in-memory store of launched tasks
+// keyed by task ID.
+type Registry struct {
+	mu    sync.RWMutex
+	tasks map[string]*Task
+}
+
+// New returns an empty registry
+func New() *Registry {
+	return &Registry{tasks: make(map[string]*Task)}
+}
+
+// Add registers a task about to be launched in state TASK_STAGING
+func (r *Registry) Add(info *mesos.TaskInfo) error {
+	id := info.GetTaskId().GetValue()
+	if id == "" {
+		return fmt.Errorf("registry: task has no ID")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if t, ok := r.tasks[id]; ok && !t.Terminal() {
+		return fmt.Errorf("registry: task %s already registered", id)
+	}
+	now := time.Now()
+	r.tasks[id] = &Task{
+		Info:    info,
+		AgentID: info.GetAgentId().GetValue(),
+		State:   mesos.TaskState_TASK_STAGING,
+		Created: now,
+		Updated: now,
+	}
+	return nil
+}
+
+// Update records a status update for a registered task. It returns
+// an error if the task is unknown or the transition is not valid.
+func (r *Registry) Update(status *mesos.TaskStatus) error {
+	id := status.GetTaskId().GetValue()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.tasks[id]
+	if !ok {
+		return fmt.Errorf("registry: unknown task %s", id)
+	}
+	if !ValidTransition(t.State, status.GetState()) {
+		return fmt.Errorf(
+			"registry: task %s cannot move from %s to %s",
+			id, t.State, status.GetState(),
+		)
+	}
+
+	now := time.Now()
+	at := now
+	if status.Timestamp != nil {
+		sec, frac := math.Modf(status.GetTimestamp())
+		at = time.Unix(int64(sec), int64(frac*1e9))
+	}
+	t.History = append(t.History, Status{
+		State:   status.GetState(),
+		Reason:  status.GetReason(),
+		Source:  status.GetSource(),
+		Message: status.GetMessage(),
+		Healthy: status.Healthy,
+		Time:    at,
+	})
+	t.State = status.GetState()
+	if agent := status.GetAgentId().GetValue(); agent != "" {
+		t.AgentID = agent
+	}
+	t.Updated = now
+	return nil
+}
+
+// RequestKill records that the framework asked to kill the task
+func (r *Registry) RequestKill(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.tasks[id]
+	if !ok {
+		return fmt.Errorf("registry: unknown task %s", id)
+	}
+	if t.Terminal() {
+		return fmt.Errorf("registry: task %s already %s", id, t.State)
+	}
+	now := time.Now()
+	t.KillRequested = now
+	t.Updated = now
+	return nil
+}
+
+// Restore puts back a task record, for instance one reloaded
+// from a state store after a scheduler restart.
+func (r *Registry) Restore(t *Task) error {
+	id := t.ID()
+	if id == "" {
+		return fmt.Errorf("registry: task has no ID")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tasks[id] = t.clone()
+	return nil
+}
+
+// Get returns a copy of the task record
+func (r *Registry) Get(id string) (*Task, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	t, ok := r.tasks[id]
+	if !ok {
+		return nil, false
+	}
+	return t.clone(), true
+}
+
+// Remove forgets a task
+func (r *Registry) Remove(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.tasks, id)
+}
+
+// Len returns the number of registered tasks
+func (r *Registry) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.tasks)
+}
+
+// Select returns copies of the tasks matching the filter
+func (r *Registry) Select(filter func(*Task) bool) []*Task {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var tasks []*Task
+	for _, t := range r.tasks {
+		if filter == nil || filter(t) {
+			tasks = append(tasks, t.clone())
+		}
+	}
+	return tasks
+}
+
+// InState returns the tasks in any of the given states
+func (r *Registry) InState(states ...mesos.TaskState) []*Task {
+	return r.Select(func(t *Task) bool {
+		return hasState(t, states)
+	})
+}
+
+// OnAgent returns the tasks on the agent, optionally limited to
+// the given states, e.g. OnAgent(id, mesos.TaskState_TASK_RUNNING).
+func (r *Registry) OnAgent(agentID string, states ...mesos.TaskState) []*Task {
+	return r.Select(func(t *Task) bool {
+		return t.AgentID == agentID && (len(states) == 0 || hasState(t, states))
+	})
+}
+
+// Active returns the tasks that have not reached a terminal state
+func (r *Registry) Active() []*Task {
+	return r.Select(func(t *Task) bool {
+		return !t.Terminal()
+	})
+}
+
+// WithLabels returns the active tasks whose labels match the selector
+func (r *Registry) WithLabels(selector map[string]string) []*Task {
+	return r.Select(func(t *Task) bool {
+		return !t.Terminal() && MatchLabels(t.Info.GetLabels(), selector)
+	})
+}
+
+// Statuses returns the latest known status of each active task,
+// suitable for an explicit reconciliation request.
+func (r *Registry) Statuses() []*mesos.TaskStatus {
+	var statuses []*mesos.TaskStatus
+	for _, t := range r.Active() {
+		statuses = append(statuses, &mesos.TaskStatus{
+			TaskId:  t.Info.GetTaskId(),
+			State:   t.State.Enum(),
+			AgentId: &mesos.AgentID{Value: proto.String(t.AgentID)},
+		})
+	}
+	return statuses
+}
+
+func hasState(t *Task, states []mesos.TaskState) bool {
+	for _, s := range states {
+		if t.State == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,151 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/vladimirvivien/mesos-http/mesos/mesos"
+)
+
+func taskInfo(id string, labels map[string]string) *mesos.TaskInfo {
+	return &mesos.TaskInfo{
+		Name:    proto.String(id),
+		TaskId:  &mesos.TaskID{Value: proto.String(id)},
+		AgentId: &mesos.AgentID{Value: proto.String("a1")},
+		Labels:  ToLabels(labels),
+	}
+}
+
+func status(id string, state mesos.TaskState) *mesos.TaskStatus {
+	return &mesos.TaskStatus{
+		TaskId: &mesos.TaskID{Value: proto.String(id)},
+		State:  state.Enum(),
+	}
+}
+
+func TestValidTransition(t *testing.T) {
+	tests := []struct {
+		from, to mesos.TaskState
+		ok       bool
+	}{
+		{mesos.TaskState_TASK_STAGING, mesos.TaskState_TASK_STARTING, true},
+		{mesos.TaskState_TASK_STAGING, mesos.TaskState_TASK_RUNNING, true},
+		{mesos.TaskState_TASK_STARTING, mesos.TaskState_TASK_RUNNING, true},
+		{mesos.TaskState_TASK_RUNNING, mesos.TaskState_TASK_KILLING, true},
+		{mesos.TaskState_TASK_STAGING, mesos.TaskState_TASK_FAILED, true},
+		{mesos.TaskState_TASK_RUNNING, mesos.TaskState_TASK_FINISHED, true},
+		{mesos.TaskState_TASK_KILLING, mesos.TaskState_TASK_KILLED, true},
+		{mesos.TaskState_TASK_RUNNING, mesos.TaskState_TASK_LOST, true},
+		// repeated states, e.g. health updates
+		{mesos.TaskState_TASK_RUNNING, mesos.TaskState_TASK_RUNNING, true},
+		// back to an earlier stage
+		{mesos.TaskState_TASK_RUNNING, mesos.TaskState_TASK_STAGING, false},
+		{mesos.TaskState_TASK_RUNNING, mesos.TaskState_TASK_STARTING, false},
+		{mesos.TaskState_TASK_KILLING, mesos.TaskState_TASK_RUNNING, false},
+		// terminal states are final
+		{mesos.TaskState_TASK_FINISHED, mesos.TaskState_TASK_RUNNING, false},
+		{mesos.TaskState_TASK_KILLED, mesos.TaskState_TASK_KILLED, false},
+		{mesos.TaskState_TASK_LOST, mesos.TaskState_TASK_FAILED, false},
+		{mesos.TaskState_TASK_ERROR, mesos.TaskState_TASK_STAGING, false},
+	}
+	for _, test := range tests {
+		if ok := ValidTransition(test.from, test.to); ok != test.ok {
+			t.Errorf("ValidTransition(%s, %s) = %v, want %v", test.from, test.to, ok, test.ok)
+		}
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	tests := []struct {
+		name    string
+		updates []mesos.TaskState
+		state   mesos.TaskState
+		history int
+		ok      bool
+	}{
+		{"running", []mesos.TaskState{mesos.TaskState_TASK_RUNNING}, mesos.TaskState_TASK_RUNNING, 1, true},
+		{"finished", []mesos.TaskState{mesos.TaskState_TASK_RUNNING, mesos.TaskState_TASK_FINISHED}, mesos.TaskState_TASK_FINISHED, 2, true},
+		{"duplicate", []mesos.TaskState{mesos.TaskState_TASK_RUNNING, mesos.TaskState_TASK_RUNNING}, mesos.TaskState_TASK_RUNNING, 2, true},
+		{"terminal then running", []mesos.TaskState{mesos.TaskState_TASK_KILLED, mesos.TaskState_TASK_RUNNING}, mesos.TaskState_TASK_KILLED, 1, false},
+		{"out of order stage", []mesos.TaskState{mesos.TaskState_TASK_RUNNING, mesos.TaskState_TASK_STARTING}, mesos.TaskState_TASK_RUNNING, 1, false},
+	}
+	for _, test := range tests {
+		r := New()
+		if err := r.Add(taskInfo("t1", nil)); err != nil {
+			t.Fatal(err)
+		}
+		var err error
+		for _, state := range test.updates {
+			if err = r.Update(status("t1", state)); err != nil {
+				break
+			}
+		}
+		if (err == nil) != test.ok {
+			t.Errorf("%s: update error %v, want ok %v", test.name, err, test.ok)
+		}
+		task, _ := r.Get("t1")
+		if task.State != test.state || len(task.History) != test.history {
+			t.Errorf("%s: task %s with %d updates, want %s with %d",
+				test.name, task.State, len(task.History), test.state, test.history)
+		}
+	}
+
+	if err := New().Update(status("unknown", mesos.TaskState_TASK_RUNNING)); err == nil {
+		t.Error("update of an unknown task recorded")
+	}
+}
+
+func TestRequestKill(t *testing.T) {
+	r := New()
+	r.Add(taskInfo("t1", nil))
+	if err := r.RequestKill("t1"); err != nil {
+		t.Fatal(err)
+	}
+	if task, _ := r.Get("t1"); task.KillRequested.IsZero() {
+		t.Error("kill request not recorded")
+	}
+
+	r.Update(status("t1", mesos.TaskState_TASK_KILLED))
+	if err := r.RequestKill("t1"); err == nil {
+		t.Error("kill of a terminated task requested")
+	}
+	if err := r.RequestKill("unknown"); err == nil {
+		t.Error("kill of an unknown task requested")
+	}
+}
+
+func TestWithLabels(t *testing.T) {
+	r := New()
+	r.Add(taskInfo("web", map[string]string{"app": "shop", "tier": "front"}))
+	r.Add(taskInfo("db", map[string]string{"app": "shop", "tier": "back"}))
+	r.Add(taskInfo("done", map[string]string{"app": "shop", "tier": "front"}))
+	r.Add(taskInfo("bare", nil))
+	r.Update(status("done", mesos.TaskState_TASK_FINISHED))
+
+	tests := []struct {
+		selector map[string]string
+		want     []string
+	}{
+		{map[string]string{"tier": "front"}, []string{"web"}},
+		{map[string]string{"app": "shop"}, []string{"db", "web"}},
+		{map[string]string{"app": "shop", "tier": "back"}, []string{"db"}},
+		{map[string]string{"app": "other"}, nil},
+		// an empty selector matches every active task
+		{nil, []string{"bare", "db", "web"}},
+	}
+	for _, test := range tests {
+		got := make(map[string]bool)
+		for _, task := range r.WithLabels(test.selector) {
+			got[task.ID()] = true
+		}
+		if len(got) != len(test.want) {
+			t.Errorf("WithLabels(%v) = %v, want %v", test.selector, got, test.want)
+			continue
+		}
+		for _, id := range test.want {
+			if !got[id] {
+				t.Errorf("WithLabels(%v) = %v, want %v", test.selector, got, test.want)
+			}
+		}
+	}
+}
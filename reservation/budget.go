@@ -0,0 +1,74 @@
+package reservation
+
+import (
+	"math"
+
+	"github.com/vladimirvivien/mesos-http/mesos/mesos"
+)
+
+// Budget accounts for the scalar resources of an offer left to launch
+// tasks with. Tasks draw on the resources reserved by the manager
+// before unreserved ones.
+type Budget struct {
+	m        *Manager
+	reserved map[string]float64
+	free     map[string]float64
+}
+
+// NewBudget returns the budget of the resources left of an offer once
+// its reservations are planned. Without manager, m is nil and all
+// scalars are drawn on as unreserved resources.
+func NewBudget(m *Manager, resources []*mesos.Resource) *Budget {
+	b := &Budget{m: m, reserved: make(map[string]float64), free: make(map[string]float64)}
+	for _, res := range resources {
+		if res.GetType() != mesos.Value_SCALAR || res.Disk != nil {
+			continue
+		}
+		amount := res.GetScalar().GetValue()
+		switch {
+		case m == nil:
+			b.free[res.GetName()] += amount
+		case m.Owns(res):
+			b.reserved[res.GetName()] += amount
+		case unreserved(res) && res.Revocable == nil:
+			b.free[res.GetName()] += amount
+		}
+	}
+	return b
+}
+
+// Available returns the amount of a resource left
+func (b *Budget) Available(name string) float64 {
+	return round(b.reserved[name] + b.free[name])
+}
+
+// Take returns the resources making up amount of a resource, or nil
+// if less is available.
+func (b *Budget) Take(name string, amount float64) []*mesos.Resource {
+	amount = round(amount)
+	if amount <= 0 || amount > b.Available(name) {
+		return nil
+	}
+	var taken []*mesos.Resource
+	if reserved := round(math.Min(amount, b.reserved[name])); reserved > 0 {
+		taken = append(taken, b.m.Resource(name, reserved))
+		b.reserved[name] -= reserved
+		amount = round(amount - reserved)
+	}
+	if amount > 0 {
+		taken = append(taken, scalar(name, amount))
+		b.free[name] -= amount
+	}
+	return taken
+}
+
+// TakeReserved returns amount of a resource reserved by the manager,
+// or nil if less is reserved.
+func (b *Budget) TakeReserved(name string, amount float64) *mesos.Resource {
+	amount = round(amount)
+	if b.m == nil || amount <= 0 || amount > round(b.reserved[name]) {
+		return nil
+	}
+	b.reserved[name] -= amount
+	return b.m.Resource(name, amount)
+}
@@ -0,0 +1,225 @@
+// Package reservation dynamically reserves offered resources for a
+// framework role, so that the role keeps its footprint on the agents
+// across restarts of the framework and of its tasks.
+package reservation
+
+import (
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/vladimirvivien/mesos-http/mesos/mesos"
+	"github.com/vladimirvivien/mesos-http/registry"
+)
+
+// Manager reserves unreserved resources of offers until the role
+// holds its target, and unreserves those exceeding it. Reservations
+// are tracked per agent.
+type Manager struct {
+	Role      string
+	Principal string
+	Labels    map[string]string
+
+	mu     sync.Mutex
+	target Target
+	agents map[string]map[string]float64
+}
+
+// NewManager returns a manager reserving target for role
+func NewManager(role, principal string, labels map[string]string, target Target) *Manager {
+	return &Manager{
+		Role:      role,
+		Principal: principal,
+		Labels:    labels,
+		target:    target,
+		agents:    make(map[string]map[string]float64),
+	}
+}
+
+// SetTarget changes the target. Resources absent from the target
+// are unreserved as they are offered.
+func (m *Manager) SetTarget(target Target) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.target = target
+}
+
+// Owns returns true if res was reserved by the manager
+func (m *Manager) Owns(res *mesos.Resource) bool {
+	info := res.GetReservation()
+	if res.GetRole() != m.Role || info == nil || info.GetPrincipal() != m.Principal {
+		return false
+	}
+	labels := info.GetLabels().GetLabels()
+	return len(labels) == len(m.Labels) && registry.MatchLabels(info.GetLabels(), m.Labels)
+}
+
+// Resource returns a scalar resource reserved by the manager
+func (m *Manager) Resource(name string, amount float64) *mesos.Resource {
+	res := scalar(name, amount)
+	res.Role = proto.String(m.Role)
+	res.Reservation = &mesos.Resource_ReservationInfo{
+		Labels: registry.ToLabels(m.Labels),
+	}
+	if m.Principal != "" {
+		res.Reservation.Principal = proto.String(m.Principal)
+	}
+	return res
+}
+
+// Plan returns the resources of offer to reserve and to unreserve.
+// The reservations of the agent are first recounted from those seen:
+// owned in offer, volumes included, and used by the active tasks on
+// the agent, so that reservations the master did not make are retried.
+// The planned operations are accounted for until the next offer from
+// the agent; the caller rolls them back if they are not sent.
+func (m *Manager) Plan(offer *mesos.Offer, used []*mesos.Resource) (reserve, unreserve []*mesos.Resource) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	agentID := offer.GetAgentId().GetValue()
+	owned, releasable, free := make(map[string]float64), make(map[string]float64), make(map[string]float64)
+	for _, res := range offer.GetResources() {
+		if res.GetType() != mesos.Value_SCALAR || res.Revocable != nil {
+			continue
+		}
+		switch {
+		case m.Owns(res):
+			owned[res.GetName()] += res.GetScalar().GetValue()
+			// volumes are destroyed before their disk is unreserved
+			if res.Disk == nil {
+				releasable[res.GetName()] += res.GetScalar().GetValue()
+			}
+		case unreserved(res) && res.Disk == nil:
+			free[res.GetName()] += res.GetScalar().GetValue()
+		}
+	}
+	for _, res := range used {
+		if res.GetType() == mesos.Value_SCALAR && res.Revocable == nil && m.Owns(res) {
+			owned[res.GetName()] += res.GetScalar().GetValue()
+		}
+	}
+	delete(m.agents, agentID)
+	for name, amount := range owned {
+		m.set(agentID, name, amount)
+	}
+
+	for _, name := range m.names() {
+		total := 0.0
+		for _, amounts := range m.agents {
+			total += amounts[name]
+		}
+		switch want := m.target[name]; {
+		case total < want:
+			amount := round(math.Min(want-total, free[name]))
+			if amount > 0 {
+				reserve = append(reserve, m.Resource(name, amount))
+				m.set(agentID, name, m.agents[agentID][name]+amount)
+			}
+		case total > want:
+			// only offered reservations can be released, those of
+			// running tasks are when the tasks terminate.
+			amount := round(math.Min(total-want, releasable[name]))
+			if amount > 0 {
+				unreserve = append(unreserve, m.Resource(name, amount))
+				m.set(agentID, name, m.agents[agentID][name]-amount)
+			}
+		}
+	}
+	return reserve, unreserve
+}
+
+// Rollback reverts the accounting of operations planned on an agent
+// that were not sent, or not accepted by the master.
+func (m *Manager) Rollback(agentID string, reserve, unreserve []*mesos.Resource) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, res := range reserve {
+		m.set(agentID, res.GetName(), m.agents[agentID][res.GetName()]-res.GetScalar().GetValue())
+	}
+	for _, res := range unreserve {
+		m.set(agentID, res.GetName(), m.agents[agentID][res.GetName()]+res.GetScalar().GetValue())
+	}
+}
+
+// Forget drops the reservations of an agent that was removed
+func (m *Manager) Forget(agentID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.agents, agentID)
+}
+
+// Reserved returns the amounts reserved on each agent
+func (m *Manager) Reserved() map[string]map[string]float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	reserved := make(map[string]map[string]float64, len(m.agents))
+	for agentID, amounts := range m.agents {
+		reserved[agentID] = make(map[string]float64, len(amounts))
+		for name, amount := range amounts {
+			reserved[agentID][name] = amount
+		}
+	}
+	return reserved
+}
+
+// Restore replaces the tracked reservations, e.g. with those
+// persisted by a previous run of the framework.
+func (m *Manager) Restore(reserved map[string]map[string]float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.agents = make(map[string]map[string]float64)
+	for agentID, amounts := range reserved {
+		for name, amount := range amounts {
+			m.set(agentID, name, amount)
+		}
+	}
+}
+
+func (m *Manager) set(agentID, name string, amount float64) {
+	amount = round(amount)
+	if amount <= 0 {
+		delete(m.agents[agentID], name)
+		if len(m.agents[agentID]) == 0 {
+			delete(m.agents, agentID)
+		}
+		return
+	}
+	if m.agents[agentID] == nil {
+		m.agents[agentID] = make(map[string]float64)
+	}
+	m.agents[agentID][name] = amount
+}
+
+// names returns the resources targeted or reserved, sorted
+func (m *Manager) names() []string {
+	seen := make(map[string]bool)
+	for name := range m.target {
+		seen[name] = true
+	}
+	for _, amounts := range m.agents {
+		for name := range amounts {
+			seen[name] = true
+		}
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// unreserved returns true if res is not reserved for any role
+func unreserved(res *mesos.Resource) bool {
+	return (res.Role == nil || res.GetRole() == "*") && res.Reservation == nil
+}
+
+func scalar(name string, amount float64) *mesos.Resource {
+	return &mesos.Resource{
+		Name:   proto.String(name),
+		Type:   mesos.Value_SCALAR.Enum(),
+		Scalar: &mesos.Value_Scalar{Value: proto.Float64(round(amount))},
+	}
+}
@@ -0,0 +1,79 @@
+package reservation
+
+import (
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/vladimirvivien/mesos-http/mesos/mesos"
+)
+
+func offer(agentID string, resources ...*mesos.Resource) *mesos.Offer {
+	return &mesos.Offer{
+		Id:        &mesos.OfferID{Value: proto.String("o-" + agentID)},
+		AgentId:   &mesos.AgentID{Value: proto.String(agentID)},
+		Resources: resources,
+	}
+}
+
+func amounts(resources []*mesos.Resource) map[string]float64 {
+	m := make(map[string]float64)
+	for _, res := range resources {
+		m[res.GetName()] += res.GetScalar().GetValue()
+	}
+	return m
+}
+
+func TestPlan(t *testing.T) {
+	m := NewManager("web", "p", map[string]string{"owner": "me"}, Target{"cpus": 2, "disk": 100})
+
+	// reservations are planned up to the target across agents
+	reserve, _ := m.Plan(offer("a1", scalar("cpus", 1), scalar("disk", 500)), nil)
+	if got := amounts(reserve); got["cpus"] != 1 || got["disk"] != 100 {
+		t.Fatalf("reserved %v on a1, want cpus 1 and disk 100", got)
+	}
+	reserve, _ = m.Plan(offer("a2", scalar("cpus", 4)), nil)
+	if got := amounts(reserve); got["cpus"] != 1 || len(got) != 1 {
+		t.Fatalf("reserved %v on a2, want cpus 1", got)
+	}
+
+	// the master did not make the reservations of a1: they are not
+	// offered nor used by tasks and are planned again
+	reserve, _ = m.Plan(offer("a1", scalar("cpus", 1), scalar("disk", 500)), nil)
+	if got := amounts(reserve); got["cpus"] != 1 || got["disk"] != 100 {
+		t.Errorf("reserved %v on a1 again, want cpus 1 and disk 100", got)
+	}
+
+	// a rolled back reservation is planned again on the next offer
+	m.Rollback("a2", []*mesos.Resource{m.Resource("cpus", 1)}, nil)
+	if got := m.Reserved()["a2"]["cpus"]; got != 0 {
+		t.Errorf("a2 holds %v cpus after rollback, want 0", got)
+	}
+	reserve, _ = m.Plan(offer("a3", scalar("cpus", 4)), nil)
+	if got := amounts(reserve); got["cpus"] != 1 {
+		t.Errorf("reserved %v on a3, want cpus 1", got)
+	}
+}
+
+func TestPlanSeen(t *testing.T) {
+	m := NewManager("web", "p", nil, Target{"cpus": 2, "disk": 100})
+	volume := m.Resource("disk", 60)
+	volume.Disk = &mesos.Resource_DiskInfo{
+		Persistence: &mesos.Resource_DiskInfo_Persistence{Id: proto.String("db")},
+	}
+
+	// reservations offered, used by tasks, or holding volumes count
+	// toward the target
+	reserve, unreserve := m.Plan(offer("a1", m.Resource("cpus", 1), volume, m.Resource("disk", 40), scalar("cpus", 4)),
+		[]*mesos.Resource{m.Resource("cpus", 1)})
+	if len(reserve) > 0 || len(unreserve) > 0 {
+		t.Errorf("planned reserve %v and unreserve %v at the target", reserve, unreserve)
+	}
+
+	// the surplus is unreserved, volumes are not
+	m.SetTarget(Target{"cpus": 1})
+	_, unreserve = m.Plan(offer("a1", m.Resource("cpus", 1), volume, m.Resource("disk", 40)),
+		[]*mesos.Resource{m.Resource("cpus", 1)})
+	if got := amounts(unreserve); got["cpus"] != 1 || got["disk"] != 40 {
+		t.Errorf("unreserved %v, want cpus 1 and disk 40", got)
+	}
+}
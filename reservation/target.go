@@ -0,0 +1,52 @@
+package reservation
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Target is the amount of each scalar resource, such as cpus, mem
+// or disk, the framework role keeps reserved across all agents.
+type Target map[string]float64
+
+// ParseTarget parses a target of the form name:amount,...
+func ParseTarget(s string) (Target, error) {
+	t := make(Target)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, ":", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("reservation: expecting name:amount, got %q", pair)
+		}
+		amount, err := strconv.ParseFloat(kv[1], 64)
+		if err != nil || !(amount >= 0) || math.IsInf(amount, 0) {
+			return nil, fmt.Errorf("reservation: invalid amount of %s: %q", kv[0], kv[1])
+		}
+		t[kv[0]] = round(amount)
+	}
+	return t, nil
+}
+
+func (t Target) String() string {
+	names := make([]string, 0, len(t))
+	for name := range t {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + ":" + strconv.FormatFloat(t[name], 'f', -1, 64)
+	}
+	return strings.Join(pairs, ",")
+}
+
+// round rounds a scalar to the three decimals Mesos keeps
+func round(v float64) float64 {
+	return math.Round(v*1000) / 1000
+}
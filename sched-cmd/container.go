@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/vladimirvivien/mesos-http/container"
+	"github.com/vladimirvivien/mesos-http/mesos/mesos"
+	"github.com/vladimirvivien/mesos-http/operation"
+	"github.com/vladimirvivien/mesos-http/queue"
+)
+
+// containerSpec returns the container of the tasks queued at
+// startup, nil without -containerizer.
+func containerSpec() (*container.Spec, error) {
+	if *containerizer == "" {
+		return nil, nil
+	}
+	spec := &container.Spec{
+		Type:       *containerizer,
+		Image:      *image,
+		Network:    *network,
+		Networks:   networkNames,
+		Privileged: *privileged,
+		ForcePull:  *forcePull,
+	}
+	for _, v := range volumes {
+		vol, err := container.ParseVolume(v)
+		if err != nil {
+			return nil, err
+		}
+		spec.Volumes = append(spec.Volumes, vol)
+	}
+	for _, p := range ports {
+		port, err := container.ParsePort(p)
+		if err != nil {
+			return nil, err
+		}
+		spec.Ports = append(spec.Ports, port)
+	}
+	for _, p := range dockerParams {
+		param, err := container.ParseParameter(p)
+		if err != nil {
+			return nil, err
+		}
+		spec.Parameters = append(spec.Parameters, param)
+	}
+	if err := spec.Check(); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+// prepare runs a task launched on an offer as its command, in its
+// container with the host ports it maps taken from the offer.
+func (s *scheduler) prepare(task *mesos.TaskInfo, pending *queue.Task, plan *operation.Plan) error {
+	task.Command = s.commandFor(pending)
+	hostPorts, ok := takePorts(pending, container.NewPortPool(plan.Remaining()))
+	if !ok {
+		return fmt.Errorf("not enough ports offered")
+	}
+	return containerize(task, pending, hostPorts)
+}
+
+// commandFor returns the command of a task, fetching its URIs
+func (s *scheduler) commandFor(pending *queue.Task) *mesos.CommandInfo {
+	if len(pending.URIs) == 0 {
+		return s.command
+	}
+	cmd := proto.Clone(s.command).(*mesos.CommandInfo)
+	cmd.Uris = append(cmd.Uris, pending.URIs...)
+	return cmd
+}
+
+// takePorts allocates from the offer the host ports a task maps
+func takePorts(pending *queue.Task, pool *container.PortPool) ([]uint32, bool) {
+	if pending.Container == nil || len(pending.Container.Ports) == 0 {
+		return nil, true
+	}
+	return pool.Take(len(pending.Container.Ports))
+}
+
+// containerize sets the container of a task, along with the ports
+// resource of its port mappings, and validates it.
+func containerize(task *mesos.TaskInfo, pending *queue.Task, hostPorts []uint32) error {
+	if pending.Container == nil {
+		return nil
+	}
+	ci, err := container.Build(pending.Container, hostPorts)
+	if err != nil {
+		return err
+	}
+	task.Container = ci
+	if len(hostPorts) > 0 {
+		task.Resources = append(task.Resources, container.PortsResource(hostPorts))
+	}
+	return container.ValidateTask(task)
+}
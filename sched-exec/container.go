@@ -0,0 +1,38 @@
+package main
+
+import (
+	"github.com/vladimirvivien/mesos-http/container"
+	"github.com/vladimirvivien/mesos-http/mesos/mesos"
+)
+
+// executorContainer returns the container the executor, and with it
+// every task, runs in; nil without -containerizer. Port mappings are
+// not supported as the executor is launched on any offer.
+func executorContainer() (*mesos.ContainerInfo, error) {
+	if *containerizer == "" {
+		return nil, nil
+	}
+	spec := &container.Spec{
+		Type:       *containerizer,
+		Image:      *image,
+		Network:    *network,
+		Networks:   networkNames,
+		Privileged: *privileged,
+		ForcePull:  *forcePull,
+	}
+	for _, v := range volumes {
+		vol, err := container.ParseVolume(v)
+		if err != nil {
+			return nil, err
+		}
+		spec.Volumes = append(spec.Volumes, vol)
+	}
+	for _, p := range dockerParams {
+		param, err := container.ParseParameter(p)
+		if err != nil {
+			return nil, err
+		}
+		spec.Parameters = append(spec.Parameters, param)
+	}
+	return container.Build(spec, nil)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"log"
+
+	"github.com/vladimirvivien/mesos-http/tasklog"
+)
+
+// tail asks the executor of a task for the last lines of the
+// task's stdout or stderr and logs them.
+func (s *scheduler) tail(taskID, stream string, lines int) error {
+	addr, err := s.executorOf(taskID)
+	if err != nil {
+		return err
+	}
+	req := &tasklog.TailRequest{TaskID: taskID, Stream: stream, Lines: lines}
+	resp := new(tasklog.TailResponse)
+	if err := s.messages.Request(addr, tasklog.TailType, req, resp, messageTimeout); err != nil {
+		return err
+	}
+
+	if resp.Error != "" {
+		log.Println("No ", resp.Stream, " for task ", resp.TaskID, ": ", resp.Error)
+		return nil
+	}
+	log.Println("Last ", len(resp.Lines), " lines of ", resp.Stream, " for task ", resp.TaskID, ":")
+	for _, line := range resp.Lines {
+		log.Println("  ", line)
+	}
+	return nil
+}
+
+// tailActive logs the output tail of every active task
+func (s *scheduler) tailActive(lines int) {
+	for _, task := range s.Registry().Active() {
+		for _, stream := range []string{tasklog.Stdout, tasklog.Stderr} {
+			go func(taskID, stream string) {
+				if err := s.tail(taskID, stream, lines); err != nil {
+					log.Println("Unable to tail ", stream, " of task ", taskID, ": ", err)
+				}
+			}(task.ID(), stream)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/vladimirvivien/mesos-http/mesos/mesos"
+	"github.com/vladimirvivien/mesos-http/mesos/sched"
+	"github.com/vladimirvivien/mesos-http/message"
+)
+
+// messageTimeout bounds the wait for replies from executors
+const messageTimeout = 10 * time.Second
+
+// sendMessage sends message data to an executor
+func (s *scheduler) sendMessage(to message.Address, data []byte) error {
+	call := &sched.Call{
+		FrameworkId: s.FrameworkID(),
+		Type:        sched.Call_MESSAGE.Enum(),
+		Message: &sched.Call_Message{
+			AgentId:    &mesos.AgentID{Value: proto.String(to.AgentID)},
+			ExecutorId: &mesos.ExecutorID{Value: proto.String(to.ExecutorID)},
+			Data:       data,
+		},
+	}
+	resp, err := s.Send(call)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("Message call returned unexpected status: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// received hands a MESSAGE event to the messaging endpoint
+func (s *scheduler) received(msg *sched.Event_Message) {
+	from := message.Address{
+		AgentID:    msg.GetAgentId().GetValue(),
+		ExecutorID: msg.GetExecutorId().GetValue(),
+	}
+	if err := s.messages.Receive(from, msg.GetData()); err != nil {
+		log.Println("Unable to handle message from executor ", from.ExecutorID, ": ", err)
+	}
+}
+
+// executorOf returns the address of the executor running a task
+func (s *scheduler) executorOf(taskID string) (message.Address, error) {
+	task, ok := s.Registry().Get(taskID)
+	if !ok {
+		return message.Address{}, fmt.Errorf("unknown task %s", taskID)
+	}
+	return message.Address{AgentID: task.AgentID, ExecutorID: s.executor.GetExecutorId().GetValue()}, nil
+}
+
+// executors returns the executors of the active tasks
+func (s *scheduler) executors() []message.Address {
+	seen := make(map[message.Address]bool)
+	var addrs []message.Address
+	for _, task := range s.Registry().Active() {
+		addr, err := s.executorOf(task.ID())
+		if err != nil || seen[addr] {
+			continue
+		}
+		seen[addr] = true
+		addrs = append(addrs, addr)
+	}
+	return addrs
+}
+
+// reloadExecutors pushes the settings of a JSON file, a map of
+// executor setting names to values, to every running executor.
+func (s *scheduler) reloadExecutors(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	req := new(message.ConfigReload)
+	if err := json.Unmarshal(data, &req.Settings); err != nil {
+		return fmt.Errorf("Invalid executor config %s: %s", path, err)
+	}
+
+	for _, addr := range s.executors() {
+		go func(addr message.Address) {
+			reply := new(message.ConfigReloaded)
+			if err := s.messages.Request(addr, message.ConfigReloadType, req, reply, messageTimeout); err != nil {
+				log.Println("Unable to reload executor on agent ", addr.AgentID, ": ", err)
+				return
+			}
+			log.Println("Executor on agent ", addr.AgentID, " reloaded ", reply.Applied)
+		}(addr)
+	}
+	return nil
+}
@@ -1,202 +1,115 @@
 package main
 
 import (
-	"encoding/json"
 	"flag"
-	"fmt"
-	"io"
 	"log"
-	"net/http"
 	"os"
-	"os/user"
+	"os/signal"
+	"syscall"
 
 	"github.com/gogo/protobuf/proto"
-	"github.com/vladimirvivien/mesos-http/client"
+	"github.com/vladimirvivien/mesos-http/container"
+	"github.com/vladimirvivien/mesos-http/fetch"
+	"github.com/vladimirvivien/mesos-http/framework"
 	"github.com/vladimirvivien/mesos-http/mesos/mesos"
-	"github.com/vladimirvivien/mesos-http/mesos/sched"
+	"github.com/vladimirvivien/mesos-http/message"
+	"github.com/vladimirvivien/mesos-http/usage"
 )
 
-// Scheduler represents a Mesos scheduler
+// scheduler launches the tasks of the framework on the executor
 type scheduler struct {
-	framework    *mesos.FrameworkInfo
-	executor     *mesos.ExecutorInfo
-	taskLaunched int
-	taskFinished int
-	maxTasks     int
+	*framework.Scheduler
+	executor *mesos.ExecutorInfo
+	command  *mesos.CommandInfo
 
-	client     *client.Client
-	cpuPerTask float64
-	memPerTask float64
-	events     chan *sched.Event
-	doneChan   chan struct{}
+	messages *message.Endpoint
+	usage    *usage.Store
 }
 
-// New returns a pointer to new Scheduler
 func newSched(master string, fw *mesos.FrameworkInfo, exec *mesos.ExecutorInfo) *scheduler {
-	return &scheduler{
-		client:     client.New(master, "/api/v1/scheduler"),
-		framework:  fw,
-		executor:   exec,
-		cpuPerTask: 1,
-		memPerTask: 128,
-		maxTasks:   5,
-		events:     make(chan *sched.Event),
-		doneChan:   make(chan struct{}),
-	}
-}
-
-// start starts the scheduler and subscribes to event stream
-// returns a channel to wait for completion.
-func (s *scheduler) start() <-chan struct{} {
-	if err := s.subscribe(); err != nil {
-		log.Fatal(err)
-	}
-	go s.handleEvents()
-	return s.doneChan
-}
-
-func (s *scheduler) stop() {
-	close(s.events)
-}
-
-func (s *scheduler) send(call *sched.Call) (*http.Response, error) {
-	payload, err := proto.Marshal(call)
-	if err != nil {
-		return nil, err
-	}
-	return s.client.Send(payload)
-}
-
-// Subscribe subscribes the scheduler to the Mesos cluster.
-// It keeps the http connection opens with the Master to stream
-// subsequent events.
-func (s *scheduler) subscribe() error {
-	call := &sched.Call{
-		Type: sched.Call_SUBSCRIBE.Enum(),
-		Subscribe: &sched.Call_Subscribe{
-			FrameworkInfo: s.framework,
-		},
-	}
-
-	resp, err := s.send(call)
-	if err != nil {
-		return err
-	}
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("Subscribe with unexpected response status: %d", resp.StatusCode)
-	}
-	log.Println("Mesos-Stream-Id:", s.client.StreamID)
-
-	go s.qEvents(resp)
-
-	return nil
-}
-
-func (s *scheduler) qEvents(resp *http.Response) {
-	defer func() {
-		resp.Body.Close()
-		close(s.events)
-	}()
-	dec := json.NewDecoder(resp.Body)
-	for {
-		event := new(sched.Event)
-		if err := dec.Decode(event); err != nil {
-			if err == io.EOF {
-				return
-			}
-			continue
-		}
-		s.events <- event
-	}
-}
-
-func (s *scheduler) handleEvents() {
-	defer close(s.doneChan)
-	for ev := range s.events {
-		switch ev.GetType() {
-
-		case sched.Event_SUBSCRIBED:
-			sub := ev.GetSubscribed()
-			s.framework.Id = sub.FrameworkId
-			log.Println("Subscribed: FrameworkID: ", sub.FrameworkId.GetValue())
-
-		case sched.Event_OFFERS:
-			offers := ev.GetOffers().GetOffers()
-			log.Println("Received ", len(offers), " offers ")
-			go s.offers(offers)
-
-		case sched.Event_RESCIND:
-			log.Println("Received rescind offers")
-
-		case sched.Event_UPDATE:
-			status := ev.GetUpdate().GetStatus()
-			go s.status(status)
-
-		case sched.Event_MESSAGE:
-			log.Println("Received message event")
-
-		case sched.Event_FAILURE:
-			log.Println("Received failure event")
-			fail := ev.GetFailure()
-			if fail.ExecutorId != nil {
-				log.Println(
-					"Executor ", fail.ExecutorId.GetValue(), " terminated ",
-					" with status ", fail.GetStatus(),
-					" on agent ", fail.GetAgentId().GetValue(),
-				)
-			} else {
-				if fail.GetAgentId() != nil {
-					log.Println("Agent ", fail.GetAgentId().GetValue(), " failed ")
-				}
-			}
-
-		case sched.Event_ERROR:
-			err := ev.GetError().GetMessage()
-			log.Println(err)
-
-		case sched.Event_HEARTBEAT:
-			log.Println("HEARTBEAT")
-		}
-
+	s := &scheduler{
+		Scheduler: framework.New(master, fw),
+		executor:  exec,
+		usage:     usage.NewStore(usageSamples),
 	}
+	s.Prepare = s.prepare
+	s.Received = s.received
+	s.Updated = s.updated
+	s.messages = message.NewEndpoint(s.sendMessage, message.JSON)
+	s.messages.Handle(usage.ReportType, s.usageReported)
+	return s
 }
 
 var (
-	master    = flag.String("master", "127.0.0.1:5050", "Master address <ip:port>")
-	execPath  = flag.String("executor", "./exec", "Path to test executor")
-	mesosUser = flag.String("user", "", "Framework user")
-	maxTasks  = flag.Int("maxtasks", 5, "Mesos authentication principal")
+	opts       = framework.Flags()
+	execPath   = flag.String("executor", "./exec", "Path to test executor")
+	cmd        = flag.String("cmd", "", "Command run by the executor for each task")
+	tailLines  = flag.Int("tail", 20, "Lines of task output logged on SIGUSR1, along with resource usage")
+	execConfig = flag.String("executor-config", "", "JSON file of executor settings pushed to executors on SIGHUP")
+	uris       fetch.URIList
+	execURIs   fetch.URIList
+
+	containerizer = flag.String("containerizer", "", "Run the executor in a docker or mesos container")
+	image         = flag.String("image", "", "Container image")
+	network       = flag.String("network", "", "Container network mode <host|bridge|none|user>")
+	privileged    = flag.Bool("privileged", false, "Run the docker container privileged")
+	forcePull     = flag.Bool("force-pull", false, "Pull the docker image even if cached")
+	volumes       container.StringList
+	dockerParams  container.StringList
+	networkNames  container.StringList
 )
 
 func init() {
-	flag.Parse()
+	flag.Var(&uris, "uri", "URI fetched by the executor for each task <uri>[;executable][;cache][;noextract][;output=path], repeatable")
+	flag.Var(&execURIs, "executor-uri", "URI fetched by the agent for the executor, same format as -uri, repeatable")
+	flag.Var(&volumes, "volume", "Container volume <[host_path:]container_path[:ro|rw]>, repeatable")
+	flag.Var(&dockerParams, "docker-param", "Docker run option <key=value>, repeatable")
+	flag.Var(&networkNames, "network-name", "Named network joined by the container, repeatable")
 }
 
 func main() {
-	if *mesosUser == "" {
-		u, err := user.Current()
-		if err != nil {
-			log.Fatal("Unable to determine user")
-		}
-		*mesosUser = u.Username
-	}
-	hostname, err := os.Hostname()
+	flag.Parse()
+	fw, err := opts.FrameworkInfo("Go-HTTP-Scheduler")
 	if err != nil {
-		hostname = "UNKNOWN"
-	}
-
-	fw := &mesos.FrameworkInfo{
-		User:     mesosUser,
-		Name:     proto.String("Go-HTTP-Scheduler"),
-		Hostname: proto.String(hostname),
+		log.Fatal(err)
 	}
 	exec := &mesos.ExecutorInfo{
 		Name:       proto.String("Go-HTTP-Executor"),
 		ExecutorId: &mesos.ExecutorID{Value: proto.String("go-http-exec")},
-		Command:    &mesos.CommandInfo{Value: proto.String(*execPath)},
+		Command:    &mesos.CommandInfo{Value: proto.String(*execPath), Uris: execURIs},
 		Source:     proto.String("go-source"),
 	}
-	sched := newSched(*master, fw, exec)
-	sched.maxTasks = *maxTasks
-	<-sched.start()
+	if exec.Container, err = executorContainer(); err != nil {
+		log.Fatal(err)
+	}
+	sched := newSched(opts.Master, fw, exec)
+	// tasks of a custom executor cannot carry a CommandInfo,
+	// the executor reads it from the task data instead
+	if *cmd != "" {
+		sched.command = &mesos.CommandInfo{
+			Shell: proto.Bool(true),
+			Value: proto.String(*cmd),
+		}
+	}
+	sched.URIs = uris
+	// the task API also serves the resource usage of tasks
+	opts.Handler = sched.Handler()
+
+	requests := make(chan os.Signal, 1)
+	signal.Notify(requests, syscall.SIGUSR1, syscall.SIGHUP)
+	go func() {
+		for sig := range requests {
+			switch {
+			case sig == syscall.SIGUSR1:
+				sched.logUsage()
+				sched.tailActive(*tailLines)
+			case *execConfig != "":
+				if err := sched.reloadExecutors(*execConfig); err != nil {
+					log.Println("Unable to reload executors: ", err)
+				}
+			}
+		}
+	}()
+
+	framework.Run(sched.Scheduler, opts)
 }
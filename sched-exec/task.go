@@ -0,0 +1,36 @@
+package main
+
+import (
+	"log"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/vladimirvivien/mesos-http/mesos/mesos"
+	"github.com/vladimirvivien/mesos-http/operation"
+	"github.com/vladimirvivien/mesos-http/queue"
+)
+
+// prepare runs a task launched on an offer with the executor
+func (s *scheduler) prepare(task *mesos.TaskInfo, pending *queue.Task, plan *operation.Plan) error {
+	task.Executor = s.executor
+	task.Data = s.taskData(pending)
+	return nil
+}
+
+// taskData returns the data of a task: the command run by the
+// executor, along with the URIs it fetches for the task.
+func (s *scheduler) taskData(pending *queue.Task) []byte {
+	if s.command == nil && len(pending.URIs) == 0 {
+		return nil
+	}
+	cmd := &mesos.CommandInfo{Shell: proto.Bool(true)}
+	if s.command != nil {
+		cmd = proto.Clone(s.command).(*mesos.CommandInfo)
+	}
+	cmd.Uris = append(cmd.Uris, pending.URIs...)
+	data, err := proto.Marshal(cmd)
+	if err != nil {
+		log.Println("Unable to encode command of task ", pending.ID, ": ", err)
+		return nil
+	}
+	return data
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/vladimirvivien/mesos-http/framework"
+	"github.com/vladimirvivien/mesos-http/mesos/mesos"
+	"github.com/vladimirvivien/mesos-http/message"
+	"github.com/vladimirvivien/mesos-http/registry"
+	"github.com/vladimirvivien/mesos-http/usage"
+)
+
+// usageSamples is the number of usage samples kept per task
+const usageSamples = 60
+
+// usageSuffix ends the path of the resource usage of a task
+const usageSuffix = "/usage"
+
+// taskUsage is the resource usage of a task served by the task API
+type taskUsage struct {
+	TaskID     string                    `json:"task_id"`
+	Cpus       float64                   `json:"cpus"`
+	Statistics *mesos.ResourceStatistics `json:"statistics"`
+}
+
+// usageReported records a usage report sent by an executor. Reports
+// may arrive after the terminal update of their task, whose samples
+// are then forgotten again.
+func (s *scheduler) usageReported(msg *message.Message) (interface{}, error) {
+	report := new(mesos.TaskStatus)
+	if err := msg.Decode(report); err != nil {
+		return nil, err
+	}
+	stats, err := usage.Statistics(report)
+	if err != nil {
+		log.Println("Ignoring usage report from executor ", msg.From.ExecutorID, ": ", err)
+		return nil, nil
+	}
+	taskID := report.GetTaskId().GetValue()
+	s.usage.Record(taskID, stats)
+	if task, ok := s.Registry().Get(taskID); !ok || task.Terminal() {
+		s.usage.Remove(taskID)
+	}
+	return nil, nil
+}
+
+// updated forgets the usage of terminated tasks
+func (s *scheduler) updated(status *mesos.TaskStatus) {
+	if registry.IsTerminal(status.GetState()) {
+		s.usage.Remove(status.GetTaskId().GetValue())
+	}
+}
+
+// Usage returns the latest resource usage reported for a task
+func (s *scheduler) Usage(taskID string) (*mesos.ResourceStatistics, bool) {
+	return s.usage.Latest(taskID)
+}
+
+// Handler returns the task API of the framework along with
+//
+//	GET /tasks/<id>/usage  answers the latest resource usage of a task
+//	                       and its cpus used since the previous report
+func (s *scheduler) Handler() http.Handler {
+	api := s.Scheduler.Handler()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, framework.TasksPath+"/") || !strings.HasSuffix(r.URL.Path, usageSuffix) {
+			api.ServeHTTP(w, r)
+			return
+		}
+		s.serveUsage(w, r)
+	})
+}
+
+func (s *scheduler) serveUsage(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, framework.TasksPath+"/"), usageSuffix)
+	if id == "" || strings.Contains(id, "/") {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	stats, ok := s.Usage(id)
+	if !ok {
+		http.Error(w, "No usage reported for task "+id, http.StatusNotFound)
+		return
+	}
+	cpus, _ := s.usage.CPUUsage(id)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(&taskUsage{TaskID: id, Cpus: cpus, Statistics: stats})
+}
+
+// logUsage logs the latest resource usage of the active tasks
+func (s *scheduler) logUsage() {
+	for _, task := range s.Registry().Active() {
+		stats, ok := s.Usage(task.ID())
+		if !ok {
+			log.Println("No usage reported for task ", task.ID())
+			continue
+		}
+		cpus, _ := s.usage.CPUUsage(task.ID())
+		log.Printf("Task %s usage: cpus %.2f (user %.2fs, system %.2fs), rss %d bytes, %d processes",
+			task.ID(), cpus, stats.GetCpusUserTimeSecs(), stats.GetCpusSystemTimeSecs(),
+			stats.GetMemRssBytes(), stats.GetProcesses())
+	}
+}
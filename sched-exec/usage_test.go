@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/vladimirvivien/mesos-http/framework"
+	"github.com/vladimirvivien/mesos-http/mesos/mesos"
+)
+
+func stats(timestamp, cpuSecs float64, rss uint64) *mesos.ResourceStatistics {
+	return &mesos.ResourceStatistics{
+		Timestamp:        proto.Float64(timestamp),
+		CpusUserTimeSecs: proto.Float64(cpuSecs),
+		MemRssBytes:      proto.Uint64(rss),
+	}
+}
+
+func TestUsageAPI(t *testing.T) {
+	s := newSched("127.0.0.1:0",
+		&mesos.FrameworkInfo{User: proto.String("u"), Name: proto.String("f")},
+		&mesos.ExecutorInfo{ExecutorId: &mesos.ExecutorID{Value: proto.String("e")}})
+	s.usage.Record("t1", stats(10, 1, 1024))
+	s.usage.Record("t1", stats(12, 2, 2048))
+
+	if latest, ok := s.Usage("t1"); !ok || latest.GetMemRssBytes() != 2048 {
+		t.Fatalf("usage %v, want the latest report", latest)
+	}
+
+	api := httptest.NewServer(s.Handler())
+	defer api.Close()
+	resp, err := http.Get(api.URL + framework.TasksPath + "/t1/usage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("usage returned %s", resp.Status)
+	}
+	got := new(taskUsage)
+	if err := json.NewDecoder(resp.Body).Decode(got); err != nil {
+		t.Fatal(err)
+	}
+	if got.TaskID != "t1" || got.Cpus != 0.5 || got.Statistics.GetMemRssBytes() != 2048 {
+		t.Errorf("usage %+v, want 0.5 cpus and 2048 bytes for t1", got)
+	}
+
+	for path, want := range map[string]int{
+		"/t2/usage": http.StatusNotFound,
+		// the framework task API is still served
+		"/t1": http.StatusMethodNotAllowed,
+	} {
+		resp, err := http.Get(api.URL + framework.TasksPath + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("GET %s returned %s, want %d", path, resp.Status, want)
+		}
+	}
+}
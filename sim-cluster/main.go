@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/vladimirvivien/mesos-http/mesostest/cluster"
+)
+
+var (
+	addr         = flag.String("addr", "127.0.0.1:5050", "Master address to listen on <ip:port>")
+	agents       = flag.Int("agents", 3, "Number of virtual agents")
+	cpus         = flag.Float64("cpus", 4, "Cpus per agent")
+	mem          = flag.Float64("mem", 1024, "Memory per agent in MB")
+	disk         = flag.Float64("disk", 0, "Disk per agent in MB, which may be reserved for volumes")
+	racks        = flag.Int("racks", 2, "Number of racks agents are spread over")
+	offerEvery   = flag.Duration("offer-interval", time.Second, "Interval between offer rounds")
+	startLatency = flag.Duration("start-latency", 500*time.Millisecond, "Delay from launch to TASK_RUNNING")
+	runTime      = flag.Duration("run-time", 2*time.Second, "Delay from TASK_RUNNING to a terminal state")
+	failureRate  = flag.Float64("failure-rate", 0, "Probability that a task fails")
+	seed         = flag.Int64("seed", 1, "Seed for task failures")
+)
+
+func init() {
+	flag.Parse()
+}
+
+func main() {
+	specs := cluster.Agents(*agents, *cpus, *mem, *racks)
+	for i := range specs {
+		specs[i].Disk = *disk
+	}
+	c, err := cluster.NewAt(*addr, cluster.Config{
+		Agents:        specs,
+		OfferInterval: *offerEvery,
+		StartLatency:  *startLatency,
+		RunTime:       *runTime,
+		FailureRate:   *failureRate,
+		Seed:          *seed,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Println("Simulated master listening on ", c.Addr(), " with ", *agents, " agents")
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	<-signals
+	log.Println("Tasks by state: ", c.Tasks())
+	c.Close()
+}
@@ -0,0 +1,223 @@
+package store
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/vladimirvivien/mesos-http/constraint"
+	"github.com/vladimirvivien/mesos-http/container"
+	"github.com/vladimirvivien/mesos-http/mesos/mesos"
+	"github.com/vladimirvivien/mesos-http/queue"
+	"github.com/vladimirvivien/mesos-http/registry"
+	"github.com/vladimirvivien/mesos-http/reservation"
+	"github.com/vladimirvivien/mesos-http/volume"
+)
+
+// StateKey is the key of the framework state
+const StateKey = "state"
+
+// State is the framework state. It is saved as a single value so
+// that a scheduler restarting after a crash never loads parts of
+// different snapshots, e.g. a task both pending and launched.
+type State struct {
+	FrameworkID  string                          `json:"framework_id,omitempty"`
+	Tasks        []taskRecord                    `json:"tasks,omitempty"`
+	Pending      []pendingRecord                 `json:"pending,omitempty"`
+	Placements   map[string]constraint.Placement `json:"placements,omitempty"`
+	Reservations map[string]map[string]float64   `json:"reservations,omitempty"`
+	Volumes      []volume.Volume                 `json:"volumes,omitempty"`
+}
+
+// Save persists the framework state
+func Save(s Store, st *State) error {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	return s.Put(StateKey, data)
+}
+
+// Load returns the persisted framework state or nil if none was
+// persisted.
+func Load(s Store) (*State, error) {
+	data, err := s.Get(StateKey)
+	if err == ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	st := new(State)
+	if err := json.Unmarshal(data, st); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+// Clear removes the framework state, e.g. after a teardown
+func Clear(s Store) error {
+	return s.Delete(StateKey)
+}
+
+// SetFrameworkID records the framework ID assigned by the master
+func (st *State) SetFrameworkID(id *mesos.FrameworkID) {
+	st.FrameworkID = id.GetValue()
+}
+
+// GetFrameworkID returns the framework ID or nil if the framework
+// never subscribed.
+func (st *State) GetFrameworkID() *mesos.FrameworkID {
+	if st.FrameworkID == "" {
+		return nil
+	}
+	return &mesos.FrameworkID{Value: proto.String(st.FrameworkID)}
+}
+
+// taskRecord is the stored form of a registry.Task
+type taskRecord struct {
+	Info    []byte
+	AgentID string
+	State   mesos.TaskState
+	History []registry.Status
+
+	KillRequested time.Time
+	Created       time.Time
+	Updated       time.Time
+}
+
+// SetRegistry records all tasks of the registry
+func (st *State) SetRegistry(r *registry.Registry) error {
+	var records []taskRecord
+	for _, t := range r.Select(nil) {
+		info, err := proto.Marshal(t.Info)
+		if err != nil {
+			return err
+		}
+		records = append(records, taskRecord{
+			Info:    info,
+			AgentID: t.AgentID,
+			State:   t.State,
+			History: t.History,
+
+			KillRequested: t.KillRequested,
+			Created:       t.Created,
+			Updated:       t.Updated,
+		})
+	}
+	st.Tasks = records
+	return nil
+}
+
+// RestoreRegistry restores the recorded tasks into the registry
+func (st *State) RestoreRegistry(r *registry.Registry) error {
+	for _, rec := range st.Tasks {
+		info := new(mesos.TaskInfo)
+		if err := proto.Unmarshal(rec.Info, info); err != nil {
+			return err
+		}
+		err := r.Restore(&registry.Task{
+			Info:    info,
+			AgentID: rec.AgentID,
+			State:   rec.State,
+			History: rec.History,
+
+			KillRequested: rec.KillRequested,
+			Created:       rec.Created,
+			Updated:       rec.Updated,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pendingRecord is the stored form of a queue.Task
+type pendingRecord struct {
+	ID          string
+	Name        string
+	Priority    int
+	Cpus        float64
+	Mem         float64
+	Constraints []string
+	Labels      map[string]string
+	URIs        []*mesos.CommandInfo_URI
+	Container   *container.Spec
+	Volume      string
+}
+
+// SetQueue records the pending tasks in dequeue order
+func (st *State) SetQueue(q *queue.Queue) {
+	var records []pendingRecord
+	for _, t := range q.Tasks() {
+		rec := pendingRecord{
+			ID:        t.ID,
+			Name:      t.Name,
+			Priority:  t.Priority,
+			Cpus:      t.Cpus,
+			Mem:       t.Mem,
+			Labels:    t.Labels,
+			URIs:      t.URIs,
+			Container: t.Container,
+			Volume:    t.Volume,
+		}
+		for _, c := range t.Constraints {
+			rec.Constraints = append(rec.Constraints, c.String())
+		}
+		records = append(records, rec)
+	}
+	st.Pending = records
+}
+
+// RestoreQueue pushes the recorded pending tasks onto the queue
+func (st *State) RestoreQueue(q *queue.Queue) error {
+	for _, rec := range st.Pending {
+		t := &queue.Task{
+			ID:        rec.ID,
+			Name:      rec.Name,
+			Priority:  rec.Priority,
+			Cpus:      rec.Cpus,
+			Mem:       rec.Mem,
+			Labels:    rec.Labels,
+			URIs:      rec.URIs,
+			Container: rec.Container,
+			Volume:    rec.Volume,
+		}
+		for _, expr := range rec.Constraints {
+			c, err := constraint.Parse(expr)
+			if err != nil {
+				return err
+			}
+			t.Constraints = append(t.Constraints, c)
+		}
+		if err := q.Push(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetReservations records the resources reserved on each agent
+func (st *State) SetReservations(m *reservation.Manager) {
+	st.Reservations = m.Reserved()
+}
+
+// RestoreReservations restores the reservations tracked by m
+func (st *State) RestoreReservations(m *reservation.Manager) {
+	if st.Reservations != nil {
+		m.Restore(st.Reservations)
+	}
+}
+
+// SetVolumes records the persistent volumes of the framework
+func (st *State) SetVolumes(m *volume.Manager) {
+	st.Volumes = m.Volumes()
+}
+
+// RestoreVolumes restores the volumes tracked by m
+func (st *State) RestoreVolumes(m *volume.Manager) {
+	if st.Volumes != nil {
+		m.Restore(st.Volumes)
+	}
+}
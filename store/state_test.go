@@ -0,0 +1,119 @@
+package store
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/vladimirvivien/mesos-http/constraint"
+	"github.com/vladimirvivien/mesos-http/mesos/mesos"
+	"github.com/vladimirvivien/mesos-http/queue"
+	"github.com/vladimirvivien/mesos-http/registry"
+)
+
+func tempStore(t *testing.T) (*FileStore, func()) {
+	dir, err := ioutil.TempDir("", "store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewFileStore(dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return s, func() { os.RemoveAll(dir) }
+}
+
+func TestSaveLoad(t *testing.T) {
+	s, cleanup := tempStore(t)
+	defer cleanup()
+
+	if st, err := Load(s); err != nil || st != nil {
+		t.Fatalf("empty store loaded %v, %v", st, err)
+	}
+	// a queue saved before the framework subscribed is state too
+	q := queue.New()
+	if err := q.Push(&queue.Task{ID: "t1", Priority: 1}); err != nil {
+		t.Fatal(err)
+	}
+	st := new(State)
+	st.SetQueue(q)
+	if err := Save(s, st); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(s)
+	if err != nil || loaded == nil {
+		t.Fatalf("loaded %v, %v", loaded, err)
+	}
+	if loaded.GetFrameworkID() != nil {
+		t.Errorf("loaded framework ID %v before subscribing", loaded.GetFrameworkID())
+	}
+
+	// the launch of the task replaces the whole state
+	r := registry.New()
+	r.Add(&mesos.TaskInfo{
+		Name:    proto.String("t1"),
+		TaskId:  &mesos.TaskID{Value: proto.String("t1")},
+		AgentId: &mesos.AgentID{Value: proto.String("a1")},
+	})
+	st = new(State)
+	st.SetFrameworkID(&mesos.FrameworkID{Value: proto.String("f1")})
+	if err := st.SetRegistry(r); err != nil {
+		t.Fatal(err)
+	}
+	st.SetQueue(queue.New())
+	if err := Save(s, st); err != nil {
+		t.Fatal(err)
+	}
+	if loaded, err = Load(s); err != nil {
+		t.Fatal(err)
+	}
+	if id := loaded.GetFrameworkID(); id.GetValue() != "f1" {
+		t.Errorf("loaded framework ID %v, want f1", id)
+	}
+	restored, pending := registry.New(), queue.New()
+	if err := loaded.RestoreRegistry(restored); err != nil {
+		t.Fatal(err)
+	}
+	if err := loaded.RestoreQueue(pending); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := restored.Get("t1"); !ok || pending.Len() != 0 {
+		t.Errorf("restored %d tasks and %d pending, want t1 launched only", restored.Len(), pending.Len())
+	}
+
+	if err := Clear(s); err != nil {
+		t.Fatal(err)
+	}
+	if st, err := Load(s); err != nil || st != nil {
+		t.Errorf("cleared store loaded %v, %v", st, err)
+	}
+}
+
+func TestPlacements(t *testing.T) {
+	s, cleanup := tempStore(t)
+	defer cleanup()
+
+	placements := map[string]constraint.Placement{
+		"t1": {
+			Hostname: "h1",
+			Attributes: []*mesos.Attribute{{
+				Name: proto.String("rack"),
+				Type: mesos.Value_TEXT.Enum(),
+				Text: &mesos.Value_Text{Value: proto.String("r1")},
+			}},
+		},
+	}
+	if err := Save(s, &State{Placements: placements}); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := loaded.Placements["t1"]
+	if p.Hostname != "h1" || len(p.Attributes) != 1 || !proto.Equal(p.Attributes[0], placements["t1"].Attributes[0]) {
+		t.Errorf("loaded placement %+v", p)
+	}
+}
@@ -0,0 +1,192 @@
+// Package tasklog captures task output in size-rotated files and
+// defines the messages used to request a tail of those files
+// from an executor.
+package tasklog
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+// Streams of a task captured by the executor
+const (
+	Stdout = "stdout"
+	Stderr = "stderr"
+)
+
+// Writer writes to a file that is rotated once it reaches maxSize:
+// path is renamed path.1, path.1 is renamed path.2 and so on, up to
+// maxFiles rotated files.
+type Writer struct {
+	path     string
+	maxSize  int64
+	maxFiles int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// NewWriter opens path for appending
+func NewWriter(path string, maxSize int64, maxFiles int) (*Writer, error) {
+	if maxSize <= 0 {
+		return nil, fmt.Errorf("tasklog: invalid max size %d", maxSize)
+	}
+	w := &Writer{path: path, maxSize: maxSize, maxFiles: maxFiles}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *Writer) open() error {
+	f, err := os.OpenFile(w.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.file = f
+	w.size = info.Size()
+	return nil
+}
+
+// Write writes p, rotating the file first if p does not fit.
+// Writes larger than maxSize are split across files.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	written := 0
+	for len(p) > 0 {
+		if w.size >= w.maxSize {
+			if err := w.rotate(); err != nil {
+				return written, err
+			}
+		}
+		chunk := p
+		if room := w.maxSize - w.size; int64(len(chunk)) > room {
+			chunk = chunk[:room]
+		}
+		n, err := w.file.Write(chunk)
+		written += n
+		w.size += int64(n)
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// rotate shifts the rotated files, dropping the oldest one
+func (w *Writer) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	w.file = nil
+
+	if w.maxFiles <= 0 {
+		if err := os.Remove(w.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return w.open()
+	}
+	os.Remove(rotated(w.path, w.maxFiles))
+	for i := w.maxFiles - 1; i >= 1; i-- {
+		if err := os.Rename(rotated(w.path, i), rotated(w.path, i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Rename(w.path, rotated(w.path, 1)); err != nil {
+		return err
+	}
+	return w.open()
+}
+
+// Close closes the current file
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+func rotated(path string, i int) string {
+	return fmt.Sprintf("%s.%d", path, i)
+}
+
+// Tail returns the last n lines written through a Writer to path,
+// reading rotated files as needed.
+func Tail(path string, maxFiles, n int) ([]string, error) {
+	var lines [][]byte
+	for i := 0; i <= maxFiles && len(lines) < n; i++ {
+		name := path
+		if i > 0 {
+			name = rotated(path, i)
+		}
+		data, err := ioutil.ReadFile(name)
+		if os.IsNotExist(err) {
+			if i == 0 {
+				return nil, err
+			}
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// a line may continue in the next, newer, file
+		if len(lines) > 0 && len(data) > 0 && data[len(data)-1] != '\n' {
+			last := bytes.LastIndexByte(data, '\n')
+			lines[0] = append(append([]byte{}, data[last+1:]...), lines[0]...)
+			data = data[:last+1]
+		}
+		data = bytes.TrimSuffix(data, []byte("\n"))
+		if len(data) == 0 {
+			continue
+		}
+		lines = append(bytes.Split(data, []byte("\n")), lines...)
+	}
+
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	tail := make([]string, len(lines))
+	for i, line := range lines {
+		tail[i] = string(line)
+	}
+	return tail, nil
+}
+
+// TailType is the message type of tail requests
+const TailType = "tasklog.tail"
+
+// TailRequest asks an executor for the last lines of a task stream
+type TailRequest struct {
+	TaskID string `json:"task_id"`
+	Stream string `json:"stream"`
+	Lines  int    `json:"lines"`
+}
+
+// TailResponse carries the requested lines, or why they could
+// not be read.
+type TailResponse struct {
+	TaskID string   `json:"task_id"`
+	Stream string   `json:"stream"`
+	Lines  []string `json:"lines,omitempty"`
+	Error  string   `json:"error,omitempty"`
+}
@@ -0,0 +1,177 @@
+package usage
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/vladimirvivien/mesos-http/mesos/mesos"
+)
+
+// cgroup locates the cgroup directories of a process, either a
+// single unified (v2) directory or one directory per v1 controller.
+type cgroup struct {
+	unified string
+	cpu     string
+	memory  string
+}
+
+// cgroupOf reads /proc/<pid>/cgroup
+func cgroupOf(proc, root string, pid int) (*cgroup, error) {
+	f, err := os.Open(filepath.Join(proc, strconv.Itoa(pid), "cgroup"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	cg := new(cgroup)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// hierarchy-ID:controller-list:path
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if parts[0] == "0" && parts[1] == "" {
+			cg.unified = filepath.Join(root, parts[2])
+			continue
+		}
+		for _, controller := range strings.Split(parts[1], ",") {
+			switch controller {
+			case "cpu":
+				cg.cpu = filepath.Join(root, parts[1], parts[2])
+			case "memory":
+				cg.memory = filepath.Join(root, parts[1], parts[2])
+			}
+		}
+	}
+	return cg, scanner.Err()
+}
+
+// fill sets the cgroup limits and counters available; cgroups of
+// other versions or controllers are skipped.
+func (cg *cgroup) fill(rs *mesos.ResourceStatistics) {
+	if cg.memory != "" || cg.cpu != "" {
+		cg.fillV1(rs)
+		return
+	}
+	if cg.unified == "" {
+		return
+	}
+	dir := cg.unified
+	if v, ok := readUint(filepath.Join(dir, "memory.current")); ok {
+		rs.MemTotalBytes = proto.Uint64(v)
+	}
+	if v, ok := readUint(filepath.Join(dir, "memory.max")); ok {
+		rs.MemLimitBytes = proto.Uint64(v)
+	}
+	if v, ok := readUint(filepath.Join(dir, "memory.high")); ok {
+		rs.MemSoftLimitBytes = proto.Uint64(v)
+	}
+	mem := readKeyed(filepath.Join(dir, "memory.stat"))
+	setUint(&rs.MemAnonBytes, mem, "anon")
+	setUint(&rs.MemFileBytes, mem, "file")
+	setUint(&rs.MemMappedFileBytes, mem, "file_mapped")
+
+	cpu := readKeyed(filepath.Join(dir, "cpu.stat"))
+	setUint32(&rs.CpusNrPeriods, cpu, "nr_periods")
+	setUint32(&rs.CpusNrThrottled, cpu, "nr_throttled")
+	if v, ok := cpu["throttled_usec"]; ok {
+		rs.CpusThrottledTimeSecs = proto.Float64(float64(v) / 1e6)
+	}
+	// cpu.max is "<quota> <period>", quota being "max" if unlimited
+	if data, err := ioutil.ReadFile(filepath.Join(dir, "cpu.max")); err == nil {
+		fields := strings.Fields(string(data))
+		if len(fields) == 2 {
+			quota, err1 := strconv.ParseFloat(fields[0], 64)
+			period, err2 := strconv.ParseFloat(fields[1], 64)
+			if err1 == nil && err2 == nil && period > 0 {
+				rs.CpusLimit = proto.Float64(quota / period)
+			}
+		}
+	}
+}
+
+func (cg *cgroup) fillV1(rs *mesos.ResourceStatistics) {
+	if dir := cg.memory; dir != "" {
+		if v, ok := readUint(filepath.Join(dir, "memory.usage_in_bytes")); ok {
+			rs.MemTotalBytes = proto.Uint64(v)
+		}
+		if v, ok := readUint(filepath.Join(dir, "memory.limit_in_bytes")); ok {
+			rs.MemLimitBytes = proto.Uint64(v)
+		}
+		if v, ok := readUint(filepath.Join(dir, "memory.soft_limit_in_bytes")); ok {
+			rs.MemSoftLimitBytes = proto.Uint64(v)
+		}
+		mem := readKeyed(filepath.Join(dir, "memory.stat"))
+		setUint(&rs.MemCacheBytes, mem, "total_cache")
+		setUint(&rs.MemMappedFileBytes, mem, "total_mapped_file")
+		setUint(&rs.MemSwapBytes, mem, "total_swap")
+	}
+	if dir := cg.cpu; dir != "" {
+		cpu := readKeyed(filepath.Join(dir, "cpu.stat"))
+		setUint32(&rs.CpusNrPeriods, cpu, "nr_periods")
+		setUint32(&rs.CpusNrThrottled, cpu, "nr_throttled")
+		if v, ok := cpu["throttled_time"]; ok {
+			rs.CpusThrottledTimeSecs = proto.Float64(float64(v) / 1e9)
+		}
+		quota, ok1 := readInt(filepath.Join(dir, "cpu.cfs_quota_us"))
+		period, ok2 := readInt(filepath.Join(dir, "cpu.cfs_period_us"))
+		if ok1 && ok2 && quota > 0 && period > 0 {
+			rs.CpusLimit = proto.Float64(float64(quota) / float64(period))
+		}
+	}
+}
+
+func readUint(path string) (uint64, bool) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, false
+	}
+	v, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	return v, err == nil
+}
+
+func readInt(path string) (int64, bool) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, false
+	}
+	v, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	return v, err == nil
+}
+
+// readKeyed reads a file of "key value" lines such as memory.stat
+func readKeyed(path string) map[string]uint64 {
+	values := make(map[string]uint64)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return values
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		if v, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+			values[fields[0]] = v
+		}
+	}
+	return values
+}
+
+func setUint(field **uint64, values map[string]uint64, key string) {
+	if v, ok := values[key]; ok {
+		*field = proto.Uint64(v)
+	}
+}
+
+func setUint32(field **uint32, values map[string]uint64, key string) {
+	if v, ok := values[key]; ok {
+		*field = proto.Uint32(uint32(v))
+	}
+}
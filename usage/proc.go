@@ -0,0 +1,126 @@
+package usage
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/vladimirvivien/mesos-http/mesos/mesos"
+)
+
+// clockTicks is USER_HZ, the unit of CPU times in /proc, which is
+// 100 on the platforms Mesos supports.
+const clockTicks = 100
+
+// procStat holds the fields of /proc/<pid>/stat used for sampling
+type procStat struct {
+	pid     int
+	pgrp    int
+	utime   uint64
+	stime   uint64
+	threads uint64
+	rss     uint64 // pages
+}
+
+// readStat parses /proc/<pid>/stat. The command name may contain
+// spaces and parentheses, so fields are counted after its last ')'.
+func readStat(proc string, pid int) (*procStat, error) {
+	data, err := ioutil.ReadFile(filepath.Join(proc, strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return nil, err
+	}
+	s := string(data)
+	end := strings.LastIndexByte(s, ')')
+	if end < 0 {
+		return nil, fmt.Errorf("usage: malformed stat of process %d", pid)
+	}
+	fields := strings.Fields(s[end+1:])
+	if len(fields) < 22 {
+		return nil, fmt.Errorf("usage: malformed stat of process %d", pid)
+	}
+	// fields[i] is field i+3 of proc(5)
+	var values [5]uint64
+	for i, index := range []int{2, 11, 12, 17, 21} {
+		v, err := strconv.ParseUint(fields[index], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("usage: malformed stat of process %d: %s", pid, err)
+		}
+		values[i] = v
+	}
+	return &procStat{
+		pid:     pid,
+		pgrp:    int(values[0]),
+		utime:   values[1],
+		stime:   values[2],
+		threads: values[3],
+		rss:     values[4],
+	}, nil
+}
+
+// groupStats returns the stats of the processes in a process group
+func groupStats(proc string, pgid int) ([]*procStat, error) {
+	entries, err := ioutil.ReadDir(proc)
+	if err != nil {
+		return nil, err
+	}
+	var stats []*procStat
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		st, err := readStat(proc, pid)
+		if err != nil {
+			// the process exited meanwhile
+			continue
+		}
+		if st.pgrp == pgid {
+			stats = append(stats, st)
+		}
+	}
+	return stats, nil
+}
+
+// Sample returns the resource usage of the process tree of a task.
+// Tasks are started in their own process group, led by pid; CPU
+// times, RSS, processes and threads are summed over the group.
+// Limits and memory breakdown come from the cgroup of pid.
+func Sample(pid int) (*mesos.ResourceStatistics, error) {
+	return sample("/proc", "/sys/fs/cgroup", pid)
+}
+
+func sample(proc, cgroupRoot string, pid int) (*mesos.ResourceStatistics, error) {
+	stats, err := groupStats(proc, pid)
+	if err != nil {
+		return nil, err
+	}
+	if len(stats) == 0 {
+		return nil, fmt.Errorf("usage: no process in group %d", pid)
+	}
+
+	var utime, stime, threads, rss uint64
+	for _, st := range stats {
+		utime += st.utime
+		stime += st.stime
+		threads += st.threads
+		rss += st.rss
+	}
+	rs := &mesos.ResourceStatistics{
+		Timestamp:          proto.Float64(float64(time.Now().UnixNano()) / float64(time.Second)),
+		Processes:          proto.Uint32(uint32(len(stats))),
+		Threads:            proto.Uint32(uint32(threads)),
+		CpusUserTimeSecs:   proto.Float64(float64(utime) / clockTicks),
+		CpusSystemTimeSecs: proto.Float64(float64(stime) / clockTicks),
+		MemRssBytes:        proto.Uint64(rss * uint64(os.Getpagesize())),
+	}
+
+	if cg, err := cgroupOf(proc, cgroupRoot, pid); err == nil {
+		cg.fill(rs)
+	}
+	return rs, nil
+}
@@ -0,0 +1,123 @@
+// Package usage samples the resource usage of task processes and
+// keeps the samples reported by executors.
+package usage
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/vladimirvivien/mesos-http/mesos/mesos"
+)
+
+// ReportType is the message type of usage reports sent by
+// executors to their scheduler. Reports are protobuf TaskStatus
+// messages of running tasks, with the ResourceStatistics sample
+// encoded in TaskStatus.data.
+const ReportType = "usage.report"
+
+// NewReport returns the usage report of a task
+func NewReport(taskID *mesos.TaskID, rs *mesos.ResourceStatistics) (*mesos.TaskStatus, error) {
+	data, err := proto.Marshal(rs)
+	if err != nil {
+		return nil, err
+	}
+	return &mesos.TaskStatus{
+		TaskId:    taskID,
+		State:     mesos.TaskState_TASK_RUNNING.Enum(),
+		Source:    mesos.TaskStatus_SOURCE_EXECUTOR.Enum(),
+		Timestamp: rs.Timestamp,
+		Data:      data,
+	}, nil
+}
+
+// Statistics decodes the sample of a usage report
+func Statistics(report *mesos.TaskStatus) (*mesos.ResourceStatistics, error) {
+	if len(report.GetData()) == 0 {
+		return nil, fmt.Errorf("usage: report of task %s has no statistics", report.GetTaskId().GetValue())
+	}
+	rs := new(mesos.ResourceStatistics)
+	if err := proto.Unmarshal(report.GetData(), rs); err != nil {
+		return nil, fmt.Errorf("usage: invalid statistics: %s", err)
+	}
+	return rs, nil
+}
+
+// Store keeps the latest usage samples of each task, up to a
+// fixed number of samples per task. It is safe for concurrent use.
+type Store struct {
+	mu      sync.Mutex
+	max     int
+	samples map[string][]*mesos.ResourceStatistics
+}
+
+// NewStore returns a store keeping up to max samples per task
+func NewStore(max int) *Store {
+	if max < 1 {
+		max = 1
+	}
+	return &Store{max: max, samples: make(map[string][]*mesos.ResourceStatistics)}
+}
+
+// Record adds a sample of a task. Samples are kept in timestamp
+// order, as reports may be handled out of order.
+func (s *Store) Record(taskID string, rs *mesos.ResourceStatistics) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	samples := s.samples[taskID]
+	i := sort.Search(len(samples), func(i int) bool {
+		return samples[i].GetTimestamp() > rs.GetTimestamp()
+	})
+	samples = append(samples, nil)
+	copy(samples[i+1:], samples[i:])
+	samples[i] = rs
+	if len(samples) > s.max {
+		samples = samples[len(samples)-s.max:]
+	}
+	s.samples[taskID] = samples
+}
+
+// Latest returns the most recent sample of a task
+func (s *Store) Latest(taskID string) (*mesos.ResourceStatistics, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	samples := s.samples[taskID]
+	if len(samples) == 0 {
+		return nil, false
+	}
+	return samples[len(samples)-1], true
+}
+
+// History returns the samples of a task, oldest first
+func (s *Store) History(taskID string) []*mesos.ResourceStatistics {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*mesos.ResourceStatistics(nil), s.samples[taskID]...)
+}
+
+// CPUUsage returns the CPUs used by a task between its last two
+// samples.
+func (s *Store) CPUUsage(taskID string) (float64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	samples := s.samples[taskID]
+	if len(samples) < 2 {
+		return 0, false
+	}
+	prev, last := samples[len(samples)-2], samples[len(samples)-1]
+	elapsed := last.GetTimestamp() - prev.GetTimestamp()
+	if elapsed <= 0 {
+		return 0, false
+	}
+	used := last.GetCpusUserTimeSecs() + last.GetCpusSystemTimeSecs() -
+		prev.GetCpusUserTimeSecs() - prev.GetCpusSystemTimeSecs()
+	return used / elapsed, true
+}
+
+// Remove forgets the samples of a task
+func (s *Store) Remove(taskID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.samples, taskID)
+}
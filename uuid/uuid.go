@@ -0,0 +1,72 @@
+// Package uuid generates and formats RFC 4122 version 4 UUIDs, as
+// used by Mesos to identify status updates.
+package uuid
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+)
+
+// UUID is a 16 byte RFC 4122 UUID
+type UUID [16]byte
+
+// NewRandom returns a random version 4 UUID
+func NewRandom() (UUID, error) {
+	var u UUID
+	if _, err := rand.Read(u[:]); err != nil {
+		return u, err
+	}
+	u[6] = (u[6] & 0x0f) | 0x40 // version 4
+	u[8] = (u[8] & 0x3f) | 0x80 // RFC 4122 variant
+	return u, nil
+}
+
+// New returns a random version 4 UUID. It panics if the system
+// random source fails.
+func New() UUID {
+	u, err := NewRandom()
+	if err != nil {
+		panic(fmt.Sprintf("uuid: %s", err))
+	}
+	return u
+}
+
+// FromBytes returns the UUID stored in b
+func FromBytes(b []byte) (UUID, error) {
+	var u UUID
+	if len(b) != len(u) {
+		return u, fmt.Errorf("uuid: invalid length %d", len(b))
+	}
+	copy(u[:], b)
+	return u, nil
+}
+
+// Parse parses the canonical form xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx
+func Parse(s string) (UUID, error) {
+	var u UUID
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return u, fmt.Errorf("uuid: invalid format %q", s)
+	}
+	hexa := s[0:8] + s[9:13] + s[14:18] + s[19:23] + s[24:]
+	if _, err := hex.Decode(u[:], []byte(hexa)); err != nil {
+		return u, fmt.Errorf("uuid: invalid format %q", s)
+	}
+	return u, nil
+}
+
+// Bytes returns the 16 bytes of the UUID, as set in TaskStatus.uuid
+func (u UUID) Bytes() []byte {
+	return append([]byte(nil), u[:]...)
+}
+
+// Version returns the UUID version
+func (u UUID) Version() int {
+	return int(u[6] >> 4)
+}
+
+// String returns the canonical form of the UUID
+func (u UUID) String() string {
+	h := hex.EncodeToString(u[:])
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
@@ -0,0 +1,197 @@
+package volume
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/vladimirvivien/mesos-http/mesos/mesos"
+	"github.com/vladimirvivien/mesos-http/reservation"
+)
+
+// Manager creates and destroys the persistent volumes of a framework
+// on the disk reserved by its reservation manager.
+type Manager struct {
+	reservations *reservation.Manager
+
+	mu      sync.Mutex
+	volumes map[string]*Volume
+}
+
+// NewManager returns a manager of the volumes on the disk reserved
+// by reservations.
+func NewManager(reservations *reservation.Manager) *Manager {
+	return &Manager{
+		reservations: reservations,
+		volumes:      make(map[string]*Volume),
+	}
+}
+
+// Create requests a new volume
+func (m *Manager) Create(spec Spec) error {
+	if err := spec.Check(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.volumes[spec.ID]; ok {
+		return fmt.Errorf("volume: %s already exists", spec.ID)
+	}
+	m.volumes[spec.ID] = &Volume{Spec: spec, State: Pending}
+	return nil
+}
+
+// Destroy requests the destruction of a volume. Volumes in use are
+// destroyed once offered again, after their tasks terminate.
+func (m *Manager) Destroy(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, ok := m.volumes[id]
+	if !ok {
+		return fmt.Errorf("volume: unknown volume %s", id)
+	}
+	if v.State == Pending {
+		delete(m.volumes, id)
+		return nil
+	}
+	v.State = Destroying
+	return nil
+}
+
+// Get returns the volume with persistence ID id
+func (m *Manager) Get(id string) (Volume, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, ok := m.volumes[id]
+	if !ok {
+		return Volume{}, false
+	}
+	return *v, true
+}
+
+// Volumes returns all volumes sorted by ID
+func (m *Manager) Volumes() []Volume {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	volumes := make([]Volume, 0, len(m.volumes))
+	for _, v := range m.volumes {
+		volumes = append(volumes, *v)
+	}
+	sort.Slice(volumes, func(i, j int) bool { return volumes[i].ID < volumes[j].ID })
+	return volumes
+}
+
+// Restore replaces the tracked volumes, e.g. with those persisted
+// by a previous run of the framework.
+func (m *Manager) Restore(volumes []Volume) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.volumes = make(map[string]*Volume, len(volumes))
+	for i := range volumes {
+		v := volumes[i]
+		m.volumes[v.ID] = &v
+	}
+}
+
+// Plan records the volumes of offer and returns the volumes to create
+// and to destroy. Volumes are created on the disk reserved in budget.
+// A volume being created on the agent of offer that is neither offered
+// nor among the resources used by the active tasks of the agent was not
+// created by the master, and is created again. Likewise, a volume
+// being destroyed is forgotten only once an offer of its agent no longer
+// holds it, and is destroyed again as long as it is offered.
+func (m *Manager) Plan(offer *mesos.Offer, budget *reservation.Budget, used []*mesos.Resource) (create, destroy []*mesos.Resource) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	agentID := offer.GetAgentId().GetValue()
+	seen := make(map[string]bool)
+	for _, res := range used {
+		if id := res.GetDisk().GetPersistence().GetId(); id != "" && m.reservations.Owns(res) {
+			seen[id] = true
+		}
+	}
+	for _, res := range offer.GetResources() {
+		id := res.GetDisk().GetPersistence().GetId()
+		if id == "" || !m.reservations.Owns(res) {
+			continue
+		}
+		seen[id] = true
+		v, ok := m.volumes[id]
+		if !ok {
+			// volumes of a previous run of the framework
+			v = &Volume{Spec: Spec{
+				ID:            id,
+				Size:          res.GetScalar().GetValue(),
+				ContainerPath: res.GetDisk().GetVolume().GetContainerPath(),
+			}}
+			m.volumes[id] = v
+		}
+		v.AgentID = agentID
+		switch v.State {
+		case Destroying:
+			destroy = append(destroy, proto.Clone(res).(*mesos.Resource))
+		default:
+			v.State = Created
+		}
+	}
+
+	for _, id := range m.ids() {
+		v := m.volumes[id]
+		if v.State == Destroying && v.AgentID == agentID && !seen[id] {
+			delete(m.volumes, id)
+			continue
+		}
+		if v.State == Creating && v.AgentID == agentID && !seen[id] {
+			v.AgentID = ""
+			v.State = Pending
+		}
+		if v.State != Pending {
+			continue
+		}
+		res := budget.TakeReserved("disk", v.Size)
+		if res == nil {
+			continue
+		}
+		res.Disk = m.disk(v)
+		create = append(create, res)
+		v.AgentID = agentID
+		v.State = Creating
+	}
+	return create, destroy
+}
+
+// Find returns the resource of volume id among resources, such as
+// those left of an offer, or nil if resources do not hold it.
+func (m *Manager) Find(resources []*mesos.Resource, id string) *mesos.Resource {
+	for _, res := range resources {
+		if res.GetDisk().GetPersistence().GetId() == id && m.reservations.Owns(res) {
+			return proto.Clone(res).(*mesos.Resource)
+		}
+	}
+	return nil
+}
+
+func (m *Manager) disk(v *Volume) *mesos.Resource_DiskInfo {
+	disk := &mesos.Resource_DiskInfo{
+		Persistence: &mesos.Resource_DiskInfo_Persistence{Id: proto.String(v.ID)},
+		Volume: &mesos.Volume{
+			ContainerPath: proto.String(v.ContainerPath),
+			Mode:          mesos.Volume_RW.Enum(),
+		},
+	}
+	if m.reservations.Principal != "" {
+		disk.Persistence.Principal = proto.String(m.reservations.Principal)
+	}
+	return disk
+}
+
+func (m *Manager) ids() []string {
+	ids := make([]string, 0, len(m.volumes))
+	for id := range m.volumes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
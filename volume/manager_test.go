@@ -0,0 +1,86 @@
+package volume
+
+import (
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/vladimirvivien/mesos-http/mesos/mesos"
+	"github.com/vladimirvivien/mesos-http/reservation"
+)
+
+func offer(agentID string, resources ...*mesos.Resource) *mesos.Offer {
+	return &mesos.Offer{
+		Id:        &mesos.OfferID{Value: proto.String("o-" + agentID)},
+		AgentId:   &mesos.AgentID{Value: proto.String(agentID)},
+		Resources: resources,
+	}
+}
+
+func plan(m *Manager, o *mesos.Offer, used ...*mesos.Resource) (create, destroy []*mesos.Resource) {
+	return m.Plan(o, reservation.NewBudget(m.reservations, o.GetResources()), used)
+}
+
+func TestPlan(t *testing.T) {
+	r := reservation.NewManager("web", "p", nil, reservation.Target{"disk": 100})
+	m := NewManager(r)
+	if err := m.Create(Spec{ID: "db", Size: 100, ContainerPath: "data"}); err != nil {
+		t.Fatal(err)
+	}
+
+	create, _ := plan(m, offer("a1", r.Resource("disk", 100)))
+	if len(create) != 1 {
+		t.Fatalf("created %v, want volume db", create)
+	}
+	vol := create[0]
+	if v, _ := m.Get("db"); v.State != Creating || v.AgentID != "a1" {
+		t.Fatalf("volume %+v, want creating on a1", v)
+	}
+
+	// offers from other agents do not tell about the volume
+	if create, _ := plan(m, offer("a2")); len(create) != 0 {
+		t.Errorf("created %v again without disk", create)
+	}
+	if v, _ := m.Get("db"); v.State != Creating {
+		t.Errorf("volume %+v, want creating", v)
+	}
+
+	// the volume used by a task is not offered, yet created
+	plan(m, offer("a1"), vol)
+	if v, _ := m.Get("db"); v.State != Creating {
+		t.Errorf("volume in use %+v, want creating", v)
+	}
+
+	// the master did not create the volume: its disk is offered back
+	create, _ = plan(m, offer("a1", r.Resource("disk", 100)))
+	if len(create) != 1 {
+		t.Fatalf("created %v, want volume db again", create)
+	}
+
+	plan(m, offer("a1", vol))
+	if v, _ := m.Get("db"); v.State != Created {
+		t.Errorf("offered volume %+v, want created", v)
+	}
+
+	if err := m.Destroy("db"); err != nil {
+		t.Fatal(err)
+	}
+	if _, destroy := plan(m, offer("a1", vol)); len(destroy) != 1 {
+		t.Errorf("destroyed %v, want volume db", destroy)
+	}
+	// the destroy may fail, e.g. when the accept is rejected
+	if v, _ := m.Get("db"); v.State != Destroying {
+		t.Errorf("volume %+v, want destroying until no longer offered", v)
+	}
+	if _, destroy := plan(m, offer("a1", vol)); len(destroy) != 1 {
+		t.Errorf("destroyed %v, want volume db again", destroy)
+	}
+	// offers of other agents do not tell about the volume
+	plan(m, offer("a2"))
+	if _, ok := m.Get("db"); !ok {
+		t.Error("volume forgotten on an offer of another agent")
+	}
+	plan(m, offer("a1", r.Resource("disk", 100)))
+	if _, ok := m.Get("db"); ok {
+		t.Error("destroyed volume still tracked")
+	}
+}
@@ -0,0 +1,90 @@
+// Package volume manages the persistent volumes a framework creates
+// on its reserved disk, so that the data of stateful tasks outlives
+// the tasks.
+package volume
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Spec describes a persistent volume
+type Spec struct {
+	// ID is the persistence ID chosen by the framework
+	ID string
+	// Size is the disk of the volume in MB
+	Size float64
+	// ContainerPath is where the volume is mounted, relative
+	// to the sandbox of the tasks using it.
+	ContainerPath string
+}
+
+// ParseSpec parses a volume of the form id:size:container_path
+func ParseSpec(s string) (*Spec, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("volume: expecting id:size:container_path, got %q", s)
+	}
+	size, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil {
+		return nil, fmt.Errorf("volume: invalid size %q", parts[1])
+	}
+	spec := &Spec{ID: parts[0], Size: size, ContainerPath: parts[2]}
+	if err := spec.Check(); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+// Check validates a spec
+func (s *Spec) Check() error {
+	switch {
+	case s.ID == "":
+		return fmt.Errorf("volume: missing persistence ID")
+	case !(s.Size > 0):
+		return fmt.Errorf("volume: %s has invalid size %v", s.ID, s.Size)
+	case s.ContainerPath == "" || path.IsAbs(s.ContainerPath) ||
+		strings.HasPrefix(path.Clean(s.ContainerPath), ".."):
+		return fmt.Errorf("volume: %s requires a container path within the sandbox, got %q", s.ID, s.ContainerPath)
+	}
+	return nil
+}
+
+// State is the lifecycle state of a volume
+type State int
+
+const (
+	// Pending volumes are created on the next offer with enough
+	// reserved disk.
+	Pending State = iota
+	// Creating volumes were created, but not yet offered
+	Creating
+	// Created volumes were offered
+	Created
+	// Destroying volumes are destroyed when offered, until an offer
+	// of their agent no longer holds them
+	Destroying
+)
+
+var stateNames = map[State]string{
+	Pending:    "PENDING",
+	Creating:   "CREATING",
+	Created:    "CREATED",
+	Destroying: "DESTROYING",
+}
+
+func (s State) String() string {
+	if name, ok := stateNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("State(%d)", int(s))
+}
+
+// Volume is a persistent volume tracked by a manager
+type Volume struct {
+	Spec
+	AgentID string
+	State   State
+}